@@ -0,0 +1,112 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cfg "sigs.k8s.io/controller-runtime/pkg/config/v1alpha1"
+)
+
+//+kubebuilder:object:root=true
+
+// ProjectConfig is the configuration file of the manager. Next to the settings every
+// controller-runtime manager understands (metrics, health probes, leader election,
+// webhook server, ...), it holds the settings of our own controller and webhooks.
+// Command-line flags take precedence over the file.
+type ProjectConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// ControllerManagerConfigurationSpec returns the configurations for controllers
+	cfg.ControllerManagerConfigurationSpec `json:",inline"`
+
+	// The namespaces the manager watches. Defaults to all namespaces.
+	// +optional
+	WatchNamespaces []string `json:"watchNamespaces,omitempty"`
+
+	// Defaults applied to CronJobs that don't set them
+	// +optional
+	CronJobDefaults CronJobDefaults `json:"cronJobDefaults,omitempty"`
+
+	// Tuning of the CronJob controller
+	// +optional
+	CronJobController CronJobControllerConfig `json:"cronJobController,omitempty"`
+
+	// Splitting of the CronJobs between the replicas of the manager
+	// +optional
+	Sharding ShardingConfig `json:"sharding,omitempty"`
+}
+
+// CronJobDefaults holds the defaults the mutating webhook applies to CronJobs.
+type CronJobDefaults struct {
+	// The number of successful finished jobs to retain. Defaults to 3.
+	// +optional
+	SuccessfulJobsHistoryLimit *int32 `json:"successfulJobsHistoryLimit,omitempty"`
+
+	// The number of failed finished jobs to retain. Defaults to 1.
+	// +optional
+	FailedJobsHistoryLimit *int32 `json:"failedJobsHistoryLimit,omitempty"`
+}
+
+// CronJobControllerConfig holds the tuning of the CronJob controller.
+type CronJobControllerConfig struct {
+	// The number of CronJobs reconciled in parallel. Defaults to 1.
+	// +optional
+	MaxConcurrentReconciles int `json:"maxConcurrentReconciles,omitempty"`
+
+	// Pacing of retries of failed reconciles
+	// +optional
+	RateLimiter RateLimiterConfig `json:"rateLimiter,omitempty"`
+}
+
+// RateLimiterConfig holds the parameters of the controller's rate limiter.
+type RateLimiterConfig struct {
+	// The delay before the first retry of a failed reconcile. Defaults to 5ms.
+	// +optional
+	BaseDelay *metav1.Duration `json:"baseDelay,omitempty"`
+
+	// The maximum delay between retries of the same CronJob. Defaults to 1000s.
+	// +optional
+	MaxDelay *metav1.Duration `json:"maxDelay,omitempty"`
+
+	// The overall number of retries per second. Defaults to 10.
+	// +optional
+	QPS *float64 `json:"qps,omitempty"`
+
+	// The overall burst of retries. Defaults to 100.
+	// +optional
+	Burst *int `json:"burst,omitempty"`
+}
+
+// ShardingConfig holds the settings of sharding mode.
+type ShardingConfig struct {
+	// The number of shards. Sharding is disabled if 0.
+	// +optional
+	Shards int `json:"shards,omitempty"`
+
+	// The namespace the shard Leases are kept in. Defaults to the namespace of the manager.
+	// +optional
+	LeaseNamespace string `json:"leaseNamespace,omitempty"`
+}
+
+// Complete returns the configuration for controller-runtime
+func (c *ProjectConfig) Complete() (cfg.ControllerManagerConfigurationSpec, error) {
+	return c.ControllerManagerConfigurationSpec, nil
+}
+
+func init() {
+	SchemeBuilder.Register(&ProjectConfig{})
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+// HasAction tells whether the CronJob runs an action, which the controller carries out
+// itself, rather than creating an object for each run.
+func (r *CronJob) HasAction() bool {
+	return r.Spec.ScaleAction != nil || r.Spec.HTTPAction != nil
+}
+
+// RecordAction adds the run of an action to the history, or updates the record of the run
+// if there is one already, dropping the oldest runs beyond MaxActionHistory.
+func (s *CronJobStatus) RecordAction(record ActionRecord) {
+	for i := range s.ActionHistory {
+		if s.ActionHistory[i].ScheduledTime.Equal(&record.ScheduledTime) {
+			s.ActionHistory[i] = record
+			return
+		}
+	}
+	s.ActionHistory = append(s.ActionHistory, record)
+	if extra := len(s.ActionHistory) - MaxActionHistory; extra > 0 {
+		s.ActionHistory = s.ActionHistory[extra:]
+	}
+}
+
+// RunningAction returns the record of the run of an action that isn't over yet, if there
+// is one. Runs of actions don't overlap, so there is at most one.
+func (s *CronJobStatus) RunningAction() *ActionRecord {
+	for i := range s.ActionHistory {
+		if s.ActionHistory[i].Result == ActionRunning {
+			return &s.ActionHistory[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+/*
+The controller runs CronJobs and ScheduledJobs with its own permissions, which are a good
+deal broader than those of most people who get to write them. So that they can't be used
+to do what their author can't, the webhooks check that the author could do themselves
+whatever the controller will do on their behalf.
+*/
+
+// accessCheck is something the controller does on behalf of the author of a CronJob.
+type accessCheck struct {
+	// the field of the CronJob that asks for it
+	fldPath    *field.Path
+	attributes authorizationv1.ResourceAttributes
+}
+
+// accessChecks lists what the controller does on behalf of the author of the CronJob.
+// Kinds the API server doesn't know are reported as invalid.
+func (v *cronJobValidator) accessChecks(cronJob *CronJob) ([]accessCheck, field.ErrorList) {
+	var checks []accessCheck
+	if cronJob.Spec.ResourceTemplate == nil && !cronJob.HasAction() {
+		checks = append(checks, jobAccess(field.NewPath("spec").Child("jobTemplate"), cronJob.Namespace))
+	}
+	if template := cronJob.Spec.ResourceTemplate; template != nil && !cronJob.HasAction() {
+		fldPath := field.NewPath("spec").Child("resourceTemplate", "object")
+		gvk, err := template.GroupVersionKind()
+		if err != nil {
+			return nil, field.ErrorList{field.Invalid(fldPath, "", err.Error())}
+		}
+		mapping, err := v.Client.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return nil, field.ErrorList{field.Invalid(fldPath.Child("kind"), gvk.Kind, err.Error())}
+		}
+		checks = append(checks, accessCheck{fldPath: fldPath, attributes: authorizationv1.ResourceAttributes{
+			Namespace: cronJob.Namespace,
+			Verb:      "create",
+			Group:     mapping.Resource.Group,
+			Version:   mapping.Resource.Version,
+			Resource:  mapping.Resource.Resource,
+		}})
+	}
+	if action := cronJob.Spec.ScaleAction; action != nil {
+		resource := map[string]string{"Deployment": "deployments", "StatefulSet": "statefulsets"}[action.TargetRef.Kind]
+		checks = append(checks, accessCheck{fldPath: field.NewPath("spec").Child("scaleAction", "targetRef"), attributes: authorizationv1.ResourceAttributes{
+			Namespace:   cronJob.Namespace,
+			Verb:        "update",
+			Group:       "apps",
+			Resource:    resource,
+			Subresource: "scale",
+			Name:        action.TargetRef.Name,
+		}})
+	}
+	if action := cronJob.Spec.HTTPAction; action != nil && action.HeadersSecretRef != nil {
+		checks = append(checks, secretAccess(field.NewPath("spec").Child("httpAction", "headersSecretRef"),
+			cronJob.Namespace, action.HeadersSecretRef.Name))
+	}
+	for i, n := range cronJob.Spec.Notifications {
+		fldPath := field.NewPath("spec").Child("notifications").Index(i)
+		if n.Webhook != nil && n.Webhook.HeadersSecretRef != nil {
+			checks = append(checks, secretAccess(fldPath.Child("webhook", "headersSecretRef"),
+				cronJob.Namespace, n.Webhook.HeadersSecretRef.Name))
+		}
+		if n.CloudEvents != nil && n.CloudEvents.HeadersSecretRef != nil {
+			checks = append(checks, secretAccess(fldPath.Child("cloudEvents", "headersSecretRef"),
+				cronJob.Namespace, n.CloudEvents.HeadersSecretRef.Name))
+		}
+		if n.Email != nil && n.Email.CredentialsSecretRef != nil {
+			checks = append(checks, secretAccess(fldPath.Child("email", "credentialsSecretRef"),
+				cronJob.Namespace, n.Email.CredentialsSecretRef.Name))
+		}
+	}
+	return checks, nil
+}
+
+// jobAccess is the check for the Jobs the controller creates from a job template.
+func jobAccess(fldPath *field.Path, namespace string) accessCheck {
+	return accessCheck{fldPath: fldPath, attributes: authorizationv1.ResourceAttributes{
+		Namespace: namespace,
+		Verb:      "create",
+		Group:     "batch",
+		Version:   "v1",
+		Resource:  "jobs",
+	}}
+}
+
+// secretAccess is the check for a Secret the controller reads, and sends on somewhere.
+func secretAccess(fldPath *field.Path, namespace, name string) accessCheck {
+	return accessCheck{fldPath: fldPath, attributes: authorizationv1.ResourceAttributes{
+		Namespace: namespace,
+		Verb:      "get",
+		Version:   "v1",
+		Resource:  "secrets",
+		Name:      name,
+	}}
+}
+
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// authorize checks that user may do what the CronJob asks for, and reports the fields
+// asking for more than that.
+func (v *cronJobValidator) authorize(ctx context.Context, user authenticationv1.UserInfo, cronJob *CronJob) (field.ErrorList, error) {
+	checks, allErrs := v.accessChecks(cronJob)
+	if len(allErrs) > 0 {
+		return allErrs, nil
+	}
+	return v.reviewAccess(ctx, user, checks)
+}
+
+// reviewAccess asks with a SubjectAccessReview per accessCheck whether user may do it, and
+// reports the fields of the checks that were denied.
+func (v *workloadValidator) reviewAccess(ctx context.Context, user authenticationv1.UserInfo, checks []accessCheck) (field.ErrorList, error) {
+	var allErrs field.ErrorList
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	for _, check := range checks {
+		attributes := check.attributes
+		review := &authorizationv1.SubjectAccessReview{Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &attributes,
+			User:               user.Username,
+			Groups:             user.Groups,
+			UID:                user.UID,
+			Extra:              extra,
+		}}
+		if err := v.Client.Create(ctx, review); err != nil {
+			return nil, err
+		}
+		if !review.Status.Allowed {
+			allErrs = append(allErrs, field.Forbidden(check.fldPath, fmt.Sprintf("%s may not %s", user.Username, describeAttributes(&attributes))))
+		}
+	}
+	return allErrs, nil
+}
+
+// describeAttributes spells out what the attributes of a SubjectAccessReview are about,
+// e.g. "create pipelineruns.tekton.dev in namespace ci".
+func describeAttributes(attributes *authorizationv1.ResourceAttributes) string {
+	resource := attributes.Resource
+	if attributes.Group != "" {
+		resource += "." + attributes.Group
+	}
+	if attributes.Subresource != "" {
+		resource += "/" + attributes.Subresource
+	}
+	if attributes.Name != "" {
+		resource += " " + attributes.Name
+	}
+	return fmt.Sprintf("%s %s in namespace %s", attributes.Verb, resource, attributes.Namespace)
+}
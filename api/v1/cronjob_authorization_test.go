@@ -0,0 +1,281 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// reviewingClient answers SubjectAccessReviews with allowed, and keeps them for a look.
+// Dry-runs fail with dryRunErr if it's set, e.g. because the quota is used up.
+type reviewingClient struct {
+	client.Client
+	allowed   func(*authorizationv1.ResourceAttributes) bool
+	reviews   []authorizationv1.SubjectAccessReviewSpec
+	dryRunErr error
+}
+
+func (c *reviewingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if review, ok := obj.(*authorizationv1.SubjectAccessReview); ok {
+		c.reviews = append(c.reviews, review.Spec)
+		review.Status.Allowed = c.allowed(review.Spec.ResourceAttributes)
+		return nil
+	}
+	createOpts := &client.CreateOptions{}
+	createOpts.ApplyOptions(opts)
+	if len(createOpts.DryRun) > 0 && c.dryRunErr != nil {
+		return c.dryRunErr
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func newReviewingValidator(allowed func(*authorizationv1.ResourceAttributes) bool) (*cronJobValidator, *reviewingClient) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Group: "tekton.dev", Version: "v1", Kind: "PipelineRun"}, meta.RESTScopeNamespace)
+	c := &reviewingClient{
+		Client:  fake.NewClientBuilder().WithRESTMapper(mapper).Build(),
+		allowed: allowed,
+	}
+	return &cronJobValidator{workloadValidator{Client: c}}, c
+}
+
+// newTestWorkloadValidator returns a validator that can decode admission requests and
+// dry-run Jobs, with a client holding objs.
+func newTestWorkloadValidator(t *testing.T, allowed func(*authorizationv1.ResourceAttributes) bool, objs ...client.Object) (workloadValidator, *reviewingClient) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	decoder, err := admission.NewDecoder(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &reviewingClient{
+		Client:  fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+		allowed: allowed,
+	}
+	return workloadValidator{Client: c, Scheme: scheme, decoder: decoder}, c
+}
+
+// admissionRequest is a request to admit obj, replacing old on updates.
+func admissionRequest(t *testing.T, operation admissionv1.Operation, namespace string, obj, old runtime.Object) admission.Request {
+	t.Helper()
+	req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: operation,
+		Namespace: namespace,
+		UserInfo:  authenticationv1.UserInfo{Username: "jane"},
+	}}
+	var err error
+	if req.Object.Raw, err = json.Marshal(obj); err != nil {
+		t.Fatal(err)
+	}
+	if old != nil {
+		if req.OldObject.Raw, err = json.Marshal(old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return req
+}
+
+func TestAuthorize(t *testing.T) {
+	user := authenticationv1.UserInfo{
+		Username: "jane",
+		Groups:   []string{"developers"},
+		Extra:    map[string]authenticationv1.ExtraValue{"scopes": {"all"}},
+	}
+	withTemplate := func(object string) *CronJob {
+		return &CronJob{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ci", Name: "nightly"},
+			Spec: CronJobSpec{
+				Schedule:         "0 0 * * *",
+				ResourceTemplate: &ResourceTemplate{Object: runtime.RawExtension{Raw: []byte(object)}},
+			},
+		}
+	}
+	pipelineRun := withTemplate(`{"apiVersion": "tekton.dev/v1", "kind": "PipelineRun"}`)
+	createPipelineRuns := authorizationv1.ResourceAttributes{
+		Namespace: "ci", Verb: "create", Group: "tekton.dev", Version: "v1", Resource: "pipelineruns",
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		v, c := newReviewingValidator(func(*authorizationv1.ResourceAttributes) bool { return true })
+		allErrs, err := v.authorize(context.Background(), user, pipelineRun)
+		if err != nil || len(allErrs) > 0 {
+			t.Fatalf("authorize() = %v, %v, want no errors", allErrs, err)
+		}
+		if len(c.reviews) != 1 {
+			t.Fatalf("%d SubjectAccessReviews, want 1", len(c.reviews))
+		}
+		review := c.reviews[0]
+		if *review.ResourceAttributes != createPipelineRuns {
+			t.Errorf("reviewed %+v, want %+v", *review.ResourceAttributes, createPipelineRuns)
+		}
+		if review.User != "jane" || len(review.Groups) != 1 || review.Extra["scopes"][0] != "all" {
+			t.Errorf("reviewed for %q %v %v, want the requester", review.User, review.Groups, review.Extra)
+		}
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		v, _ := newReviewingValidator(func(*authorizationv1.ResourceAttributes) bool { return false })
+		allErrs, err := v.authorize(context.Background(), user, pipelineRun)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(allErrs) != 1 || allErrs[0].Type != field.ErrorTypeForbidden || allErrs[0].Field != "spec.resourceTemplate.object" {
+			t.Errorf("authorize() = %v, want spec.resourceTemplate.object forbidden", allErrs)
+		}
+	})
+
+	t.Run("unknown kind", func(t *testing.T) {
+		v, c := newReviewingValidator(func(*authorizationv1.ResourceAttributes) bool { return true })
+		allErrs, err := v.authorize(context.Background(), user, withTemplate(`{"apiVersion": "example.com/v1", "kind": "Widget"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(allErrs) != 1 || allErrs[0].Type != field.ErrorTypeInvalid {
+			t.Errorf("authorize() = %v, want the kind invalid", allErrs)
+		}
+		if len(c.reviews) != 0 {
+			t.Errorf("%d SubjectAccessReviews, want none", len(c.reviews))
+		}
+	})
+
+	t.Run("scale action", func(t *testing.T) {
+		scaleDeployments := authorizationv1.ResourceAttributes{
+			Namespace: "ci", Verb: "update", Group: "apps", Resource: "deployments", Subresource: "scale", Name: "web",
+		}
+		v, c := newReviewingValidator(func(attributes *authorizationv1.ResourceAttributes) bool {
+			return attributes.Name != "web"
+		})
+		allErrs, err := v.authorize(context.Background(), user, &CronJob{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ci", Name: "nightly"},
+			Spec: CronJobSpec{ScaleAction: &ScaleAction{
+				TargetRef: ScaleTargetReference{Kind: "Deployment", Name: "web"},
+			}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(allErrs) != 1 || allErrs[0].Type != field.ErrorTypeForbidden || allErrs[0].Field != "spec.scaleAction.targetRef" {
+			t.Errorf("authorize() = %v, want spec.scaleAction.targetRef forbidden", allErrs)
+		}
+		if len(c.reviews) != 1 || *c.reviews[0].ResourceAttributes != scaleDeployments {
+			t.Errorf("reviewed %+v, want %+v", c.reviews, scaleDeployments)
+		}
+	})
+
+	t.Run("secret of an HTTP action", func(t *testing.T) {
+		getSecret := authorizationv1.ResourceAttributes{
+			Namespace: "ci", Verb: "get", Version: "v1", Resource: "secrets", Name: "api-token",
+		}
+		v, c := newReviewingValidator(func(*authorizationv1.ResourceAttributes) bool { return false })
+		allErrs, err := v.authorize(context.Background(), user, &CronJob{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ci", Name: "nightly"},
+			Spec: CronJobSpec{HTTPAction: &HTTPAction{
+				URL:              "https://api.example.com",
+				HeadersSecretRef: &corev1.LocalObjectReference{Name: "api-token"},
+			}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(allErrs) != 1 || allErrs[0].Type != field.ErrorTypeForbidden || allErrs[0].Field != "spec.httpAction.headersSecretRef" {
+			t.Errorf("authorize() = %v, want spec.httpAction.headersSecretRef forbidden", allErrs)
+		}
+		if len(c.reviews) != 1 || *c.reviews[0].ResourceAttributes != getSecret {
+			t.Errorf("reviewed %+v, want %+v", c.reviews, getSecret)
+		}
+	})
+
+	t.Run("secrets of notifications", func(t *testing.T) {
+		v, c := newReviewingValidator(func(attributes *authorizationv1.ResourceAttributes) bool {
+			return attributes.Resource != "secrets" || attributes.Name == "events-token"
+		})
+		cronJob := &CronJob{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ci", Name: "nightly"},
+			Spec: CronJobSpec{Notifications: []Notification{
+				{Name: "slack", Webhook: &WebhookSink{
+					URL:              "https://hooks.slack.com/services/T0",
+					HeadersSecretRef: &corev1.LocalObjectReference{Name: "slack-token"},
+				}},
+				{Name: "events", CloudEvents: &CloudEventsSink{
+					URL:              "https://broker.example.com",
+					HeadersSecretRef: &corev1.LocalObjectReference{Name: "events-token"},
+				}},
+				{Name: "mail", Email: &EmailSink{
+					SMTPServer:           "smtp.example.com:587",
+					CredentialsSecretRef: &corev1.LocalObjectReference{Name: "smtp-credentials"},
+				}},
+				{Name: "anonymous", Webhook: &WebhookSink{URL: "https://hooks.example.com"}},
+			}},
+		}
+		allErrs, err := v.authorize(context.Background(), user, cronJob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"spec.notifications[0].webhook.headersSecretRef", "spec.notifications[2].email.credentialsSecretRef"}
+		if len(allErrs) != len(want) {
+			t.Fatalf("authorize() = %v, want %v forbidden", allErrs, want)
+		}
+		for i, err := range allErrs {
+			if err.Type != field.ErrorTypeForbidden || err.Field != want[i] {
+				t.Errorf("error %d = %v, want %s forbidden", i, err, want[i])
+			}
+		}
+		// the Jobs, and the three Secrets
+		if len(c.reviews) != 4 {
+			t.Errorf("%d SubjectAccessReviews, want 4", len(c.reviews))
+		}
+	})
+
+	t.Run("Jobs", func(t *testing.T) {
+		createJobs := authorizationv1.ResourceAttributes{
+			Namespace: "ci", Verb: "create", Group: "batch", Version: "v1", Resource: "jobs",
+		}
+		v, c := newReviewingValidator(func(*authorizationv1.ResourceAttributes) bool { return false })
+		allErrs, err := v.authorize(context.Background(), user, &CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: "ci", Name: "nightly"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(allErrs) != 1 || allErrs[0].Type != field.ErrorTypeForbidden || allErrs[0].Field != "spec.jobTemplate" {
+			t.Errorf("authorize() = %v, want spec.jobTemplate forbidden", allErrs)
+		}
+		if len(c.reviews) != 1 || *c.reviews[0].ResourceAttributes != createJobs {
+			t.Errorf("reviewed %+v, want %+v", c.reviews, createJobs)
+		}
+	})
+}
@@ -0,0 +1,298 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron"
+)
+
+// scheduleDescriptors are the predefined schedules of cron, spelled out.
+var scheduleDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// scheduleField describes one of the five fields of a cron expression.
+type scheduleField struct {
+	unit  string
+	names []string
+}
+
+func (f scheduleField) name(value string) string {
+	for _, name := range f.names {
+		if name != "" && strings.EqualFold(value, name[:3]) {
+			return name
+		}
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || f.names == nil || n < 0 || n >= len(f.names) {
+		return value
+	}
+	return f.names[n]
+}
+
+var (
+	minuteField     = scheduleField{unit: "minute"}
+	hourField       = scheduleField{unit: "hour"}
+	dayOfMonthField = scheduleField{unit: "day-of-month"}
+	monthField      = scheduleField{unit: "month", names: []string{
+		"", "January", "February", "March", "April", "May", "June",
+		"July", "August", "September", "October", "November", "December"}}
+	dayOfWeekField = scheduleField{unit: "day-of-week", names: []string{
+		"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}}
+)
+
+// IsSuspended tells whether the CronJob must not start runs at the given time, either
+// because of Suspend or because of SuspendUntil.
+func (r *CronJob) IsSuspended(now time.Time) bool {
+	if r.Spec.Suspend != nil && *r.Spec.Suspend {
+		return true
+	}
+	return r.Spec.SuspendUntil != nil && now.Before(r.Spec.SuspendUntil.Time)
+}
+
+// IsExpired tells whether the ActiveUntil of the CronJob has passed at the given time.
+func (r *CronJob) IsExpired(now time.Time) bool {
+	return r.Spec.ActiveUntil != nil && !now.Before(r.Spec.ActiveUntil.Time)
+}
+
+/*
+NextRuns returns up to count scheduled times of the CronJob after now, skipping those
+that fall into a timed suspension or outside of the active range. Suspend is not taken
+into account, since the runs would just as well happen once the CronJob is resumed.
+*/
+func (r *CronJob) NextRuns(now time.Time, count int) ([]time.Time, error) {
+	sched, err := cron.ParseStandard(r.Spec.Schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	// ticks right at the end of a suspension or the start of the active range count,
+	// and cron only looks for later ones
+	from := now
+	if until := r.Spec.SuspendUntil; until != nil && from.Before(until.Time) {
+		from = until.Add(-time.Second)
+	}
+	if activeFrom := r.Spec.ActiveFrom; activeFrom != nil && from.Before(activeFrom.Time) {
+		from = activeFrom.Add(-time.Second)
+	}
+
+	var runs []time.Time
+	for t := sched.Next(from); !t.IsZero() && len(runs) < count && !r.IsExpired(t); t = sched.Next(t) {
+		runs = append(runs, t)
+	}
+	return runs, nil
+}
+
+/*
+ShortestInterval returns the shortest time between two consecutive ticks of the schedule
+over the year from its next tick after now, which is as long as a run can take without
+running into the next one, or zero if it doesn't tick twice in that time. A year covers
+every combination of the cron fields apart from the odd leap day. It stops early as soon
+as it finds an interval shorter than atLeast, or one of a minute, which cron schedules
+don't go below and @every ones don't vary from, and after maxIntervalTicks.
+*/
+func ShortestInterval(schedule string, now time.Time, atLeast time.Duration) (time.Duration, error) {
+	sched, err := cron.ParseStandard(schedule)
+	if err != nil {
+		return 0, err
+	}
+
+	var shortest time.Duration
+	prev := sched.Next(now)
+	end := prev.AddDate(1, 0, 0)
+	for ticks := 0; !prev.IsZero() && prev.Before(end) && ticks < maxIntervalTicks; ticks++ {
+		next := sched.Next(prev)
+		if next.IsZero() {
+			break
+		}
+		if interval := next.Sub(prev); shortest == 0 || interval < shortest {
+			shortest = interval
+			if shortest < atLeast || shortest <= time.Minute {
+				break
+			}
+		}
+		prev = next
+	}
+	return shortest, nil
+}
+
+// maxIntervalTicks is the most ticks ShortestInterval looks at, which is a year of an
+// hourly schedule and then some. Schedules that tick more often than that show their
+// shortest interval long before.
+const maxIntervalTicks = 10000
+
+/*
+DescribeSchedule spells out a cron schedule in English, e.g. "0 2 * * 1-5" becomes
+"At 02:00 on Monday through Friday". It understands everything `cron.ParseStandard`
+does and returns its error for schedules it rejects.
+*/
+func DescribeSchedule(schedule string) (string, error) {
+	if _, err := cron.ParseStandard(schedule); err != nil {
+		return "", err
+	}
+	if every := strings.TrimPrefix(schedule, "@every "); every != schedule {
+		return "Every " + every, nil
+	}
+	if spelled, ok := scheduleDescriptors[schedule]; ok {
+		schedule = spelled
+	}
+
+	fields := strings.Fields(schedule)
+	minute, hour, dom, month, dow := fields[0], fields[1], fields[2], fields[3], fields[4]
+
+	var b strings.Builder
+	switch {
+	case isSingleValue(minute) && isSingleValue(hour):
+		m, _ := strconv.Atoi(minute)
+		h, _ := strconv.Atoi(hour)
+		fmt.Fprintf(&b, "At %02d:%02d", h, m)
+	case isEvery(minute) && isEvery(hour):
+		b.WriteString("Every minute")
+	case isEvery(minute):
+		fmt.Fprintf(&b, "Every minute past %s", describeField(hour, hourField))
+	case isEvery(hour):
+		fmt.Fprintf(&b, "At %s", describeField(minute, minuteField))
+	default:
+		fmt.Fprintf(&b, "At %s past %s",
+			describeField(minute, minuteField), describeField(hour, hourField))
+	}
+
+	// cron runs on days matching either field when both of them are restricted
+	switch {
+	case !isEvery(dom) && !isEvery(dow):
+		fmt.Fprintf(&b, " on %s or on %s",
+			describeField(dom, dayOfMonthField), describeField(dow, dayOfWeekField))
+	case !isEvery(dom):
+		fmt.Fprintf(&b, " on %s", describeField(dom, dayOfMonthField))
+	case !isEvery(dow):
+		fmt.Fprintf(&b, " on %s", describeField(dow, dayOfWeekField))
+	}
+	if !isEvery(month) {
+		fmt.Fprintf(&b, " in %s", describeField(month, monthField))
+	}
+	return b.String(), nil
+}
+
+// scheduleWarnings returns admission warnings for schedules that are valid, but
+// probably don't do what their author had in mind.
+func scheduleWarnings(schedule string, now time.Time) []string {
+	sched, err := cron.ParseStandard(schedule)
+	if err != nil {
+		return nil
+	}
+	spelled, ok := scheduleDescriptors[schedule]
+	if !ok {
+		spelled = schedule
+	}
+
+	var warnings []string
+	if fields := strings.Fields(spelled); len(fields) == 5 {
+		dom, dow := fields[2], fields[4]
+		if !isEvery(dom) && !isEvery(dow) {
+			warnings = append(warnings, fmt.Sprintf(
+				"spec.schedule restricts both day-of-month (%s) and day-of-week (%s): the CronJob runs on days matching either of them, not both",
+				dom, dow))
+		}
+	}
+	if sched.Next(now).IsZero() {
+		warnings = append(warnings, fmt.Sprintf("spec.schedule %q never fires", schedule))
+	}
+	return warnings
+}
+
+func isEvery(expr string) bool {
+	return expr == "*" || expr == "?"
+}
+
+func isSingleValue(expr string) bool {
+	_, err := strconv.Atoi(expr)
+	return err == nil
+}
+
+// describeField describes a single field of a cron expression, like "1-5" or "*/15".
+// Plain values of numeric fields are prefixed with their unit, e.g. "minute 15".
+func describeField(expr string, f scheduleField) string {
+	var parts []string
+	for _, item := range strings.Split(expr, ",") {
+		parts = append(parts, describeFieldItem(item, f))
+	}
+	if f.names == nil && !strings.HasPrefix(parts[0], "every ") {
+		parts[0] = f.unit + " " + parts[0]
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return strings.Join(parts[:len(parts)-1], ", ") + " and " + parts[len(parts)-1]
+}
+
+func describeFieldItem(item string, f scheduleField) string {
+	rng, step, stepped := strings.Cut(item, "/")
+	var from, through string
+	switch {
+	case isEvery(rng):
+	case strings.Contains(rng, "-"):
+		lo, hi, _ := strings.Cut(rng, "-")
+		from, through = f.name(lo), f.name(hi)
+	default:
+		from = f.name(rng)
+	}
+
+	if !stepped {
+		if through != "" {
+			return from + " through " + through
+		}
+		return from
+	}
+	every := fmt.Sprintf("every %s %s", ordinal(step), f.unit)
+	switch {
+	case through != "":
+		return fmt.Sprintf("%s from %s through %s", every, from, through)
+	case from != "":
+		return fmt.Sprintf("%s from %s", every, from)
+	default:
+		return every
+	}
+}
+
+func ordinal(n string) string {
+	i, err := strconv.Atoi(n)
+	if err != nil {
+		return n
+	}
+	suffix := "th"
+	switch {
+	case i%100 >= 11 && i%100 <= 13:
+	case i%10 == 1:
+		suffix = "st"
+	case i%10 == 2:
+		suffix = "nd"
+	case i%10 == 3:
+		suffix = "rd"
+	}
+	return n + suffix
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDescribeSchedule(t *testing.T) {
+	for schedule, want := range map[string]string{
+		"15 2 * * 1-5":       "At 02:15 on Monday through Friday",
+		"* * * * *":          "Every minute",
+		"*/15 * * * *":       "At every 15th minute",
+		"0 */2 * * *":        "At minute 0 past every 2nd hour",
+		"* 9 * * *":          "Every minute past hour 9",
+		"0,30 9-17 * * MON":  "At minute 0 and 30 past hour 9 through 17 on Monday",
+		"0 0 1,15 * *":       "At 00:00 on day-of-month 1 and 15",
+		"0 0 1 * 0":          "At 00:00 on day-of-month 1 or on Sunday",
+		"30 6 * JAN-MAR/2 *": "At 06:30 in every 2nd month from January through March",
+		"@weekly":            "At 00:00 on Sunday",
+		"@every 1h30m":       "Every 1h30m",
+	} {
+		got, err := DescribeSchedule(schedule)
+		if err != nil {
+			t.Errorf("DescribeSchedule(%q) failed: %v", schedule, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("DescribeSchedule(%q) = %q, want %q", schedule, got, want)
+		}
+	}
+
+	if _, err := DescribeSchedule("every tuesday"); err == nil {
+		t.Error("DescribeSchedule accepted a malformed schedule")
+	}
+}
+
+func TestScheduleWarnings(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	for schedule, want := range map[string]int{
+		"0 0 * * *":    0,
+		"@monthly":     0,
+		"0 0 13 * FRI": 1,
+		"0 0 30 2 *":   1,
+	} {
+		if got := scheduleWarnings(schedule, now); len(got) != want {
+			t.Errorf("scheduleWarnings(%q) = %q, want %d warnings", schedule, got, want)
+		}
+	}
+}
+
+func TestNextRuns(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 30, 0, 0, time.UTC)
+	at := func(day, hour int) *metav1.Time {
+		return &metav1.Time{Time: time.Date(2023, 1, day, hour, 0, 0, 0, time.UTC)}
+	}
+	for name, tc := range map[string]struct {
+		spec CronJobSpec
+		want []time.Time
+	}{
+		"plain": {
+			spec: CronJobSpec{Schedule: "0 * * * *"},
+			want: []time.Time{at(1, 13).Time, at(1, 14).Time, at(1, 15).Time},
+		},
+		"suspended until": {
+			spec: CronJobSpec{Schedule: "0 * * * *", SuspendUntil: at(2, 9)},
+			want: []time.Time{at(2, 9).Time, at(2, 10).Time, at(2, 11).Time},
+		},
+		"active range": {
+			spec: CronJobSpec{Schedule: "0 0 * * *", ActiveFrom: at(3, 0), ActiveUntil: at(4, 12)},
+			want: []time.Time{at(3, 0).Time, at(4, 0).Time},
+		},
+		"expired": {
+			spec: CronJobSpec{Schedule: "0 0 * * *", ActiveUntil: at(1, 0)},
+		},
+	} {
+		cronJob := &CronJob{Spec: tc.spec}
+		got, err := cronJob.NextRuns(now, 3)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: NextRuns() = %v, want %v", name, got, tc.want)
+		}
+	}
+}
+
+func TestShortestInterval(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	for name, tc := range map[string]struct {
+		schedule string
+		atLeast  time.Duration
+		want     time.Duration
+	}{
+		"hourly":           {"0 * * * *", 0, time.Hour},
+		"every 15 minutes": {"*/15 * * * *", 0, 15 * time.Minute},
+		"weekdays":         {"0 2 * * 1-5", 0, 24 * time.Hour},
+		"uneven minutes":   {"0,50 * * * *", 0, 10 * time.Minute},
+		"office hours":     {"0,10 9-17 * * MON", 0, 10 * time.Minute},
+		"twice a month":    {"0 0 1,28 * *", 0, 24 * time.Hour},
+		"weekly":           {"@weekly", 0, 7 * 24 * time.Hour},
+		"yearly":           {"@yearly", 0, 366 * 24 * time.Hour}, // 2024 is a leap year
+		"every 90 minutes": {"@every 90m", 0, 90 * time.Minute},
+		"every 30 seconds": {"@every 30s", 0, 30 * time.Second},
+		"stops early":      {"*/5 * * * *", time.Hour, 5 * time.Minute},
+		"never":            {"0 0 30 2 *", 0, 0},
+		"the 31st":         {"0 0 31 * *", 0, 31 * 24 * time.Hour},
+	} {
+		got, err := ShortestInterval(tc.schedule, now, tc.atLeast)
+		if err != nil {
+			t.Errorf("%s: ShortestInterval(%q) failed: %v", name, tc.schedule, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: ShortestInterval(%q) = %s, want %s", name, tc.schedule, got, tc.want)
+		}
+	}
+	if _, err := ShortestInterval("every hour", now, 0); err == nil {
+		t.Error("ShortestInterval() took an invalid schedule")
+	}
+}
@@ -17,9 +17,12 @@ limitations under the License.
 package v1
 
 import (
+	"time"
+
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -37,6 +40,52 @@ type CronJobSpec struct {
 	// +optional
 	StartingDeadlineSeconds *int64 `json:"startingDeadlineSeconds,omitempty"`
 
+	// The CronJob doesn't run before this time
+	// +optional
+	ActiveFrom *metav1.Time `json:"activeFrom,omitempty"`
+
+	// The CronJob doesn't run from this time on. It is marked Expired then and
+	// handled according to ExpirePolicy.
+	// +optional
+	ActiveUntil *metav1.Time `json:"activeUntil,omitempty"`
+
+	// Specifies what happens to the CronJob once ActiveUntil has passed.
+	// Valid values are:
+	// - "Suspend" (default): the CronJob is suspended;
+	// - "Delete": the CronJob is deleted, together with its Jobs
+	// +optional
+	ExpirePolicy ExpirePolicy `json:"expirePolicy,omitempty"`
+
+	//+kubebuilder:validation:Minimum=1
+
+	// The number of runs after which the CronJob is Completed and stops running.
+	// Which runs count is up to RunCountPolicy; runs started by hand never do.
+	// Unlimited if not specified.
+	// +optional
+	MaxRuns *int32 `json:"maxRuns,omitempty"`
+
+	// Specifies which runs count towards MaxRuns.
+	// Valid values are:
+	// - "Succeeded" (default): only runs whose Job succeeded;
+	// - "All": every finished run, whether it succeeded or failed
+	// +optional
+	RunCountPolicy RunCountPolicy `json:"runCountPolicy,omitempty"`
+
+	// Specifies what happens to the CronJob once it is Completed.
+	// Valid values are:
+	// - "Keep" (default): the CronJob is kept around, but doesn't run anymore;
+	// - "Delete": the CronJob is deleted, together with its Jobs
+	// +optional
+	CompletionPolicy CompletionPolicy `json:"completionPolicy,omitempty"`
+
+	// Specifies how to treat an edit of the schedule.
+	// Valid values are:
+	// - "Honor" (default): missed runs are counted from the last run against the new schedule,
+	// which may fire a run right away;
+	// - "ResetAnchor": missed runs are counted from the moment the change was observed
+	// +optional
+	ScheduleChangePolicy ScheduleChangePolicy `json:"scheduleChangePolicy,omitempty"`
+
 	// Specifies how to treat concurrent executions of a Job.
 	// Valid values are:
 	// - "Allow" (default): allows CronJobs to run concurrently;
@@ -45,13 +94,100 @@ type CronJobSpec struct {
 	// +optional
 	ConcurrencyPolicy ConcurrencyPolicy `json:"concurrencyPolicy,omitempty"`
 
+	//+kubebuilder:validation:Minimum=1
+
+	// The maximum number of Jobs that may be active at the same time when the
+	// ConcurrencyPolicy is "Allow". Runs beyond it are skipped. Unlimited if not specified.
+	// +optional
+	MaxConcurrentRuns *int32 `json:"maxConcurrentRuns,omitempty"`
+
 	// This flag tells the controller to suspend subsequent executions, it does
 	// not apply to already started executions. Defaults to false.
 	// +optional
 	Suspend *bool `json:"suspend,omitempty"`
 
-	// Specifies the job that will be created when executing a CronJob
-	JobTemplate batchv1.JobTemplateSpec `json:"jobTemplate"`
+	// Suspends subsequent executions until the given time. The controller clears it,
+	// together with SuspendReason, once that time has passed. The latest run that fell
+	// into the suspension is then treated like any other missed run, see
+	// StartingDeadlineSeconds; the ones before it are skipped.
+	// +optional
+	SuspendUntil *metav1.Time `json:"suspendUntil,omitempty"`
+
+	//+kubebuilder:validation:MaxLength=256
+
+	// Why the CronJob is suspended, for the humans looking at it
+	// +optional
+	SuspendReason string `json:"suspendReason,omitempty"`
+
+	//+kubebuilder:validation:Minimum=0
+	//+kubebuilder:validation:Maximum=20
+
+	// The number of upcoming run times to publish in the status. Defaults to 3.
+	// +optional
+	UpcomingRunsLimit *int32 `json:"upcomingRunsLimit,omitempty"`
+
+	// Sets the CronJob name, the scheduled time, the run ID (the name of the Job), the attempt
+	// and the trigger of each run as annotations of its pods and as CRONJOB_* environment
+	// variables of their containers. Defaults to false.
+	// +optional
+	InjectRunMetadata *bool `json:"injectRunMetadata,omitempty"`
+
+	// Sets of parameters to run the Job with. Each tick creates one Job per entry, with the
+	// entry's name appended to the Job name. History limits and the concurrency policy apply
+	// to the Jobs of each entry separately. A single Job per tick if empty.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Matrix []MatrixEntry `json:"matrix,omitempty"`
+
+	// How long a run is expected to take. Runs going longer are reported as overdue.
+	// +optional
+	ExpectedDuration *metav1.Duration `json:"expectedDuration,omitempty"`
+
+	// How long a run may take at most. Runs going longer are reported as overdue as well,
+	// but more urgently; stopping them is up to the activeDeadlineSeconds of the Job.
+	// +optional
+	MaxDuration *metav1.Duration `json:"maxDuration,omitempty"`
+
+	// How long the CronJob may go without a successful run, e.g. 26h for a daily one,
+	// before it's reported as stale. Runs that don't happen at all count as much as
+	// failed ones, whatever the reason; suspending the CronJob doesn't change that.
+	// +optional
+	SuccessDeadline *metav1.Duration `json:"successDeadline,omitempty"`
+
+	// Suspends the CronJob when its runs keep failing
+	// +optional
+	FailurePolicy *FailurePolicy `json:"failurePolicy,omitempty"`
+
+	// Where to send notifications about the runs of the CronJob
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Notifications []Notification `json:"notifications,omitempty"`
+
+	// Specifies the job that will be created when executing a CronJob.
+	// Exactly one of JobTemplate, ResourceTemplate, ScaleAction and HTTPAction must be set.
+	// +optional
+	JobTemplate batchv1.JobTemplateSpec `json:"jobTemplate,omitempty"`
+
+	// Specifies an object of any other kind to create when executing a CronJob, in place
+	// of a Job. Exactly one of JobTemplate, ResourceTemplate, ScaleAction and HTTPAction
+	// must be set.
+	// +optional
+	ResourceTemplate *ResourceTemplate `json:"resourceTemplate,omitempty"`
+
+	// Specifies replicas to set on a Deployment or StatefulSet when executing a CronJob.
+	// The controller does that itself, in place of running a Job, and records each
+	// change in status.actionHistory. Exactly one of JobTemplate, ResourceTemplate,
+	// ScaleAction and HTTPAction must be set.
+	// +optional
+	ScaleAction *ScaleAction `json:"scaleAction,omitempty"`
+
+	// Specifies an HTTP request to send when executing a CronJob. The controller sends it
+	// itself, in place of running a Job, and records each response in status.actionHistory.
+	// Exactly one of JobTemplate, ResourceTemplate, ScaleAction and HTTPAction must be set.
+	// +optional
+	HTTPAction *HTTPAction `json:"httpAction,omitempty"`
 
 	//+kubebuilder:validation:Minimum=0
 
@@ -68,6 +204,197 @@ type CronJobSpec struct {
 	FailedJobsHistoryLimit *int32 `json:"failedJobHistoryLimit,omitempty"`
 }
 
+// ResourceTemplate describes the object to create for each run of a CronJob that doesn't
+// run Jobs, and how to tell when it's finished.
+type ResourceTemplate struct {
+	// The object to create. It needs an apiVersion and a kind; its name and namespace are
+	// set by the controller, like those of Jobs.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:EmbeddedResource
+	Object runtime.RawExtension `json:"object"`
+
+	// Status conditions that mark the object as succeeded, any one of them will do.
+	// Defaults to a "Succeeded" condition with status "True".
+	// +optional
+	SuccessConditions []ResourceCondition `json:"successConditions,omitempty"`
+
+	// Status conditions that mark the object as failed, any one of them will do.
+	// Defaults to a "Succeeded" condition with status "False".
+	// +optional
+	FailureConditions []ResourceCondition `json:"failureConditions,omitempty"`
+}
+
+// ResourceCondition matches an entry of the `status.conditions` of an object.
+type ResourceCondition struct {
+	// The type of the condition, e.g. "Ready"
+	Type string `json:"type"`
+
+	// The status the condition needs to have, one of "True", "False" or "Unknown"
+	// +kubebuilder:validation:Enum=True;False;Unknown
+	Status metav1.ConditionStatus `json:"status"`
+}
+
+// ScaleAction sets the replicas of a workload.
+type ScaleAction struct {
+	// The workload to scale, in the namespace of the CronJob
+	TargetRef ScaleTargetReference `json:"targetRef"`
+
+	//+kubebuilder:validation:Minimum=0
+
+	// The number of replicas to scale the workload to
+	Replicas int32 `json:"replicas"`
+}
+
+// ScaleTargetReference points at a workload with a scale subresource.
+type ScaleTargetReference struct {
+	// The kind of the workload
+	// +kubebuilder:validation:Enum=Deployment;StatefulSet
+	Kind string `json:"kind"`
+
+	// The name of the workload
+	Name string `json:"name"`
+}
+
+// HTTPAction sends an HTTP request.
+type HTTPAction struct {
+	// The URL to send the request to
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+
+	// The method of the request. Defaults to POST.
+	// +kubebuilder:validation:Enum=GET;HEAD;POST;PUT;PATCH;DELETE
+	// +optional
+	Method string `json:"method,omitempty"`
+
+	// A Secret in the namespace of the CronJob holding the headers of the request, one per
+	// key, e.g. "Authorization".
+	// +optional
+	HeadersSecretRef *corev1.LocalObjectReference `json:"headersSecretRef,omitempty"`
+
+	// The body of the request
+	// +optional
+	Body string `json:"body,omitempty"`
+
+	// How long to wait for a response to each attempt. Defaults to 10s.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// The status codes that make the request a success. Defaults to any 2xx code.
+	// +optional
+	ExpectedStatusCodes []int32 `json:"expectedStatusCodes,omitempty"`
+
+	//+kubebuilder:validation:Minimum=0
+	//+kubebuilder:validation:Maximum=10
+
+	// How often to retry a request that failed, or got an unexpected response. Defaults to 0.
+	// +optional
+	Retries *int32 `json:"retries,omitempty"`
+}
+
+// FailurePolicy is a circuit breaker: it suspends a CronJob whose runs keep failing, rather
+// than have it fail on every tick until someone notices.
+type FailurePolicy struct {
+	//+kubebuilder:validation:Minimum=1
+
+	// The number of runs in a row that have to fail for the CronJob to be suspended
+	MaxConsecutiveFailures int32 `json:"maxConsecutiveFailures"`
+
+	// How long to keep the CronJob suspended before resuming it. It stays suspended until
+	// someone resumes it if not set, or if someone else had suspended it already.
+	// +optional
+	CoolDown *metav1.Duration `json:"coolDown,omitempty"`
+}
+
+// Notification sends notifications about some events to a sink. Exactly one of the sinks
+// must be set.
+type Notification struct {
+	// The name of the notification, unique within the CronJob
+	Name string `json:"name"`
+
+	// The events to notify about. A run that makes for several of them is notified about
+	// once, as the most specific one: RetryExhausted, then FirstFailure, then Failure.
+	// +kubebuilder:validation:MinItems=1
+	Events []NotificationEvent `json:"events"`
+
+	// Posts a JSON payload that Slack's incoming webhooks understand
+	// +optional
+	Webhook *WebhookSink `json:"webhook,omitempty"`
+
+	// Sends an email
+	// +optional
+	Email *EmailSink `json:"email,omitempty"`
+
+	// Posts a CloudEvent in binary content mode
+	// +optional
+	CloudEvents *CloudEventsSink `json:"cloudEvents,omitempty"`
+}
+
+// NotificationEvent is something about the runs of a CronJob worth telling someone.
+// +kubebuilder:validation:Enum=Failure;FirstFailure;Recovery;MissedDeadline;RetryExhausted;Stale
+type NotificationEvent string
+
+const (
+	// FailureEvent is a failed run.
+	FailureEvent NotificationEvent = "Failure"
+
+	// FirstFailureEvent is a failed run after a successful one, or the first run failing.
+	FirstFailureEvent NotificationEvent = "FirstFailure"
+
+	// RecoveryEvent is a successful run after a failed one.
+	RecoveryEvent NotificationEvent = "Recovery"
+
+	// MissedDeadlineEvent is a run that missed its starting deadline.
+	MissedDeadlineEvent NotificationEvent = "MissedDeadline"
+
+	// RetryExhaustedEvent is a run that failed after all of its retries: a Job that
+	// reached its backoff limit, or an HTTP action that ran out of retries.
+	RetryExhaustedEvent NotificationEvent = "RetryExhausted"
+
+	// StaleEvent is the CronJob going without a successful run for longer than its
+	// SuccessDeadline.
+	StaleEvent NotificationEvent = "Stale"
+)
+
+// WebhookSink posts notifications as JSON.
+type WebhookSink struct {
+	// The URL to post to
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+
+	// A Secret in the namespace of the CronJob holding headers to send, one per key
+	// +optional
+	HeadersSecretRef *corev1.LocalObjectReference `json:"headersSecretRef,omitempty"`
+}
+
+// EmailSink sends notifications by email.
+type EmailSink struct {
+	// The SMTP server, as host:port
+	SMTPServer string `json:"smtpServer"`
+
+	// The sender of the emails
+	From string `json:"from"`
+
+	// The recipients of the emails
+	// +kubebuilder:validation:MinItems=1
+	To []string `json:"to"`
+
+	// A Secret in the namespace of the CronJob holding the "username" and "password" to log
+	// in to the server with. No authentication if not set.
+	// +optional
+	CredentialsSecretRef *corev1.LocalObjectReference `json:"credentialsSecretRef,omitempty"`
+}
+
+// CloudEventsSink posts notifications as CloudEvents over HTTP.
+type CloudEventsSink struct {
+	// The URL to post to
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+
+	// A Secret in the namespace of the CronJob holding headers to send, one per key
+	// +optional
+	HeadersSecretRef *corev1.LocalObjectReference `json:"headersSecretRef,omitempty"`
+}
+
 // ConcurrencyPolicy describes how the job will be handled.
 // Only one fo the following concurrent policies may be specified.
 // If none of the following policies is specified, the default one
@@ -87,6 +414,132 @@ const (
 	ReplaceConcurrent ConcurrencyPolicy = "Replace"
 )
 
+// MatrixEntry is one set of parameters a CronJob runs its Job with.
+type MatrixEntry struct {
+	//+kubebuilder:validation:MinLength=1
+	//+kubebuilder:validation:MaxLength=20
+	//+kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+
+	// The name of the entry, appended to the names of its Jobs
+	Name string `json:"name"`
+
+	// Environment variables to set in all containers of the Job, on top of the ones of
+	// the template
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+}
+
+// ScheduleChangePolicy describes how a change of the schedule is treated.
+// If none of the following policies is specified, the default one
+// is HonorScheduleChange.
+// +kubebuilder:validation:Enum=Honor;ResetAnchor
+type ScheduleChangePolicy string
+
+const (
+	// HonorScheduleChange keeps catching up on runs the new schedule would have
+	// made since the last run.
+	HonorScheduleChange ScheduleChangePolicy = "Honor"
+
+	// ResetAnchorScheduleChange only considers runs of the new schedule after the
+	// moment the change was observed.
+	ResetAnchorScheduleChange ScheduleChangePolicy = "ResetAnchor"
+)
+
+// ExpirePolicy describes what happens to a CronJob after its ActiveUntil.
+// Only one of the following expire policies may be specified.
+// If none of the following policies is specified, the default one
+// is SuspendOnExpiry.
+// +kubebuilder:validation:Enum=Suspend;Delete
+type ExpirePolicy string
+
+const (
+	// SuspendOnExpiry suspends the CronJob.
+	SuspendOnExpiry ExpirePolicy = "Suspend"
+
+	// DeleteOnExpiry deletes the CronJob.
+	DeleteOnExpiry ExpirePolicy = "Delete"
+)
+
+// RunCountPolicy describes which runs count towards the MaxRuns of a CronJob.
+// Only one of the following run count policies may be specified.
+// If none of the following policies is specified, the default one
+// is CountSucceededRuns.
+// +kubebuilder:validation:Enum=Succeeded;All
+type RunCountPolicy string
+
+const (
+	// CountSucceededRuns counts the runs whose Job succeeded.
+	CountSucceededRuns RunCountPolicy = "Succeeded"
+
+	// CountAllRuns counts every finished run.
+	CountAllRuns RunCountPolicy = "All"
+)
+
+// CompletionPolicy describes what happens to a CronJob after its MaxRuns.
+// Only one of the following completion policies may be specified.
+// If none of the following policies is specified, the default one
+// is KeepOnCompletion.
+// +kubebuilder:validation:Enum=Keep;Delete
+type CompletionPolicy string
+
+const (
+	// KeepOnCompletion keeps the CronJob.
+	KeepOnCompletion CompletionPolicy = "Keep"
+
+	// DeleteOnCompletion deletes the CronJob.
+	DeleteOnCompletion CompletionPolicy = "Delete"
+)
+
+const (
+	// ExpiredCondition is True once the ActiveUntil of the CronJob has passed.
+	ExpiredCondition = "Expired"
+
+	// CompletedCondition is True once the CronJob has made its MaxRuns.
+	CompletedCondition = "Completed"
+
+	// CircuitOpenCondition is True while the FailurePolicy keeps the CronJob suspended.
+	CircuitOpenCondition = "CircuitOpen"
+
+	// RunOverdueCondition is True while a run takes longer than the ExpectedDuration or
+	// MaxDuration of the CronJob.
+	RunOverdueCondition = "RunOverdue"
+
+	// StaleCondition is True while the CronJob has gone without a successful run for
+	// longer than its SuccessDeadline.
+	StaleCondition = "Stale"
+
+	// DurationExceedsIntervalCondition is True while the p95 duration of the recent runs
+	// is longer than the time between two runs of the schedule.
+	DurationExceedsIntervalCondition = "DurationExceedsInterval"
+)
+
+const (
+	// DefaultUpcomingRunsLimit is the number of upcoming runs published in the status
+	// when UpcomingRunsLimit isn't specified.
+	DefaultUpcomingRunsLimit = 3
+
+	// MaxUpcomingRunsLimit caps UpcomingRunsLimit.
+	MaxUpcomingRunsLimit = 20
+)
+
+// DefaultHTTPTimeout is the timeout of HTTP actions when Timeout isn't specified.
+const DefaultHTTPTimeout = 10 * time.Second
+
+// MaxConsecutiveFailedJobs is the number of Jobs kept in status.consecutiveFailedJobs.
+const MaxConsecutiveFailedJobs = 10
+
+// MaxActionHistory is the number of runs of actions kept in status.actionHistory.
+const MaxActionHistory = 10
+
+// MaxRecentDurations is the number of runs kept in status.recentDurations, which the
+// duration statistics are worked out over.
+const MaxRecentDurations = 20
+
+// ConfirmReplaceAnnotation must be set to "true" by the very update that switches the
+// ConcurrencyPolicy to Replace while Jobs are still active, since the next run
+// will cancel them. An annotation left over from an earlier update doesn't count.
+const ConfirmReplaceAnnotation = "batch.tutorial.kubebuilder.io/confirm-replace"
+
 // CronJobStatus defines the observed state of CronJob
 type CronJobStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
@@ -99,10 +552,229 @@ type CronJobStatus struct {
 	// Information when was the list time the job was successfully scheduled
 	// +optional
 	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+
+	// The latest available observations of the CronJob's state
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// The number of runs whose Job succeeded. Unlike the Jobs, which are pruned
+	// according to the history limits, the count is kept for the life of the CronJob.
+	// +optional
+	SucceededRuns int64 `json:"succeededRuns,omitempty"`
+
+	// The number of runs whose Job failed
+	// +optional
+	FailedRuns int64 `json:"failedRuns,omitempty"`
+
+	// The scheduled time of the latest run included in SucceededRuns and FailedRuns.
+	// Every run scheduled up to then is counted, except the UncountedRunTimes.
+	// +optional
+	LastCountedRunTime *metav1.Time `json:"lastCountedRunTime,omitempty"`
+
+	// The scheduled times of runs up to LastCountedRunTime that were still going when
+	// later runs were counted. They are counted once they finish.
+	// +optional
+	UncountedRunTimes []metav1.Time `json:"uncountedRunTimes,omitempty"`
+
+	// Whether the latest run included in SucceededRuns and FailedRuns "Succeeded" or "Failed"
+	// +optional
+	LastResult string `json:"lastResult,omitempty"`
+
+	// The state of the Jobs of each matrix entry
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Matrix []MatrixEntryStatus `json:"matrix,omitempty"`
+
+	// The schedule spelled out in English, e.g. "At 02:15 on Monday through Friday"
+	// +optional
+	ScheduleDescription string `json:"scheduleDescription,omitempty"`
+
+	// The next times the CronJob is scheduled to run, as far as they can be told from the
+	// spec, which has neither a time zone nor blackout windows: the schedule is worked out
+	// in the time zone of the controller. Empty while the CronJob is suspended or completed.
+	// +optional
+	UpcomingRuns []metav1.Time `json:"upcomingRuns,omitempty"`
+
+	// The schedule the controller last observed
+	// +optional
+	ObservedSchedule string `json:"observedSchedule,omitempty"`
+
+	// Incremented each time the controller observes a new schedule. Jobs carry the
+	// generation of the schedule that produced them in an annotation.
+	// +optional
+	ScheduleGeneration int64 `json:"scheduleGeneration,omitempty"`
+
+	// Information when the controller last observed a change of the schedule
+	// +optional
+	LastScheduleChangeTime *metav1.Time `json:"lastScheduleChangeTime,omitempty"`
+
+	// The end of the latest SuspendUntil, which the catch-up on the runs that fell into
+	// the suspension starts from
+	// +optional
+	LastSuspendUntil *metav1.Time `json:"lastSuspendUntil,omitempty"`
+
+	// When a run of the CronJob last succeeded. Unlike the Jobs, it's kept for the life
+	// of the CronJob.
+	// +optional
+	LastSuccessfulTime *metav1.Time `json:"lastSuccessfulTime,omitempty"`
+
+	// The number of the latest counted runs that failed in a row
+	// +optional
+	ConsecutiveFailures int32 `json:"consecutiveFailures,omitempty"`
+
+	// The names of the Jobs of the latest runs that failed in a row, at most
+	// MaxConsecutiveFailedJobs of them
+	// +optional
+	ConsecutiveFailedJobs []string `json:"consecutiveFailedJobs,omitempty"`
+
+	// Whether the FailurePolicy suspended the CronJob while its circuit is open, which is
+	// when it resumes the CronJob after the cool-down. A CronJob someone else suspended
+	// stays suspended.
+	// +optional
+	SuspendedByBreaker bool `json:"suspendedByBreaker,omitempty"`
+
+	// The scheduled time of the latest run that missed its starting deadline
+	// +optional
+	LastMissedRunTime *metav1.Time `json:"lastMissedRunTime,omitempty"`
+
+	// The latest runs of the action of the CronJob, oldest first. Actions are carried out
+	// by the controller itself, so there are no Jobs to tell about them; the history
+	// stands in for those.
+	// +optional
+	ActionHistory []ActionRecord `json:"actionHistory,omitempty"`
+
+	// How long the latest runs took, oldest first, at most MaxRecentDurations of them
+	// +optional
+	RecentDurations []metav1.Duration `json:"recentDurations,omitempty"`
+
+	// Statistics of RecentDurations
+	// +optional
+	DurationStatistics *DurationStatistics `json:"durationStatistics,omitempty"`
+}
+
+// ActionResult is the outcome of a run of an action.
+type ActionResult string
+
+const (
+	// ActionSucceeded means the action did what it was supposed to.
+	ActionSucceeded ActionResult = "Succeeded"
+
+	// ActionFailed means the action couldn't be carried out.
+	ActionFailed ActionResult = "Failed"
+
+	// ActionRunning means the action was started, but isn't over yet. The record is
+	// written before the controller changes anything, so that a retry of the run can
+	// tell what was there before.
+	ActionRunning ActionResult = "Running"
+)
+
+// ActionRecord describes a run of an action.
+type ActionRecord struct {
+	// The scheduled time of the run
+	ScheduledTime metav1.Time `json:"scheduledTime"`
+
+	// When the controller carried out the action, once it's over
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Whether the action "Succeeded" or "Failed", or is still "Running"
+	Result ActionResult `json:"result"`
+
+	// Why the action failed
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// What a scale action changed, so that it can be reverted
+	// +optional
+	Scale *ScaleRecord `json:"scale,omitempty"`
+
+	// The response to an HTTP action
+	// +optional
+	HTTP *HTTPRecord `json:"http,omitempty"`
+}
+
+// HTTPRecord describes the response to the last attempt of an HTTP action.
+type HTTPRecord struct {
+	// The status code of the response, if there was one
+	// +optional
+	StatusCode int32 `json:"statusCode,omitempty"`
+
+	// How long the response took
+	Latency metav1.Duration `json:"latency"`
+
+	// The number of attempts made, including retries. An attempt is counted before its
+	// request is sent.
+	Attempts int32 `json:"attempts"`
+
+	// When the next attempt is due, while the run waits to retry
+	// +optional
+	NextAttemptTime *metav1.Time `json:"nextAttemptTime,omitempty"`
+}
+
+// ScaleRecord describes a change of the replicas of a workload.
+type ScaleRecord struct {
+	// The kind of the workload
+	Kind string `json:"kind"`
+
+	// The name of the workload
+	Name string `json:"name"`
+
+	// The replicas of the workload before the change
+	PreviousReplicas int32 `json:"previousReplicas"`
+
+	// The replicas of the workload after the change
+	Replicas int32 `json:"replicas"`
+}
+
+// DurationStatistics sums up how long the recent runs of a CronJob took.
+type DurationStatistics struct {
+	// The number of runs the statistics are over
+	Runs int32 `json:"runs"`
+
+	// The median duration
+	P50 metav1.Duration `json:"p50"`
+
+	// The duration 95% of the runs took at most
+	P95 metav1.Duration `json:"p95"`
+
+	// The longest duration
+	Max metav1.Duration `json:"max"`
+}
+
+// MatrixEntryStatus is the observed state of the Jobs of one matrix entry.
+type MatrixEntryStatus struct {
+	// The name of the matrix entry
+	Name string `json:"name"`
+
+	// A list of pointers to the currently running jobs of the entry.
+	// +optional
+	Active []corev1.ObjectReference `json:"active,omitempty"`
+
+	// Information when the entry was last scheduled
+	// +optional
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+
+	// The latest tick the entry got a Job for, or was skipped at on purpose, e.g. by the
+	// concurrency policy. Unlike LastScheduleTime, it outlives the Jobs, so that only
+	// ticks after it are caught up on.
+	// +optional
+	LastHandledTime *metav1.Time `json:"lastHandledTime,omitempty"`
+
+	// Whether the latest finished Job of the entry "Succeeded" or "Failed"
+	// +optional
+	LastResult string `json:"lastResult,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`
+//+kubebuilder:printcolumn:name="Description",type=string,JSONPath=`.status.scheduleDescription`
+//+kubebuilder:printcolumn:name="Suspend",type=boolean,JSONPath=`.spec.suspend`
+//+kubebuilder:printcolumn:name="Last Schedule",type=date,JSONPath=`.status.lastScheduleTime`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // CronJob is the Schema for the cronjobs API
 type CronJob struct {
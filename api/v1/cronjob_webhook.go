@@ -17,21 +17,62 @@ limitations under the License.
 package v1
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
 	"github.com/robfig/cron"
+	admissionv1 "k8s.io/api/admission/v1"
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	validationutils "k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
 // log is for logging in this package.
 var cronjoblog = logf.Log.WithName("cronjob-resource")
 
-func (r *CronJob) SetupWebhookWithManager(mgr ctrl.Manager) error {
+const validateCronJobPath = "/validate-batch-tutorial-kubebuilder-io-v1-cronjob"
+
+// SetupWebhookWithManager registers the CronJob webhooks with the manager. When
+// watchNamespaces are given, CronJobs in other namespaces are admitted without checks,
+// since they belong to another instance of the operator.
+func (r *CronJob) SetupWebhookWithManager(mgr ctrl.Manager, watchNamespaces ...string) error {
+	decoder, err := admission.NewDecoder(mgr.GetScheme())
+	if err != nil {
+		return err
+	}
+
+	// The validating webhook is registered by hand, ahead of the builder, because some of
+	// its checks need to talk to the API server. The builder notices the path is already
+	// handled and only wires up the defaulting webhook.
+	mgr.GetWebhookServer().Register(validateCronJobPath, &webhook.Admission{
+		Handler: &cronJobValidator{workloadValidator{
+			Client:          mgr.GetClient(),
+			Scheme:          mgr.GetScheme(),
+			WatchNamespaces: watchNamespaces,
+			decoder:         decoder,
+		}},
+	})
+
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(r).
 		Complete()
@@ -43,6 +84,13 @@ func (r *CronJob) SetupWebhookWithManager(mgr ctrl.Manager) error {
 
 var _ webhook.Defaulter = &CronJob{}
 
+// The history limits Default sets on CronJobs that don't specify them. The manager
+// overrides them from its configuration file.
+var (
+	DefaultSuccessfulJobsHistoryLimit int32 = 3
+	DefaultFailedJobsHistoryLimit     int32 = 1
+)
+
 // Default implements webhook.Defaulter so a webhook will be registered for the type
 func (r *CronJob) Default() {
 	cronjoblog.Info("default", "name", r.Name)
@@ -51,16 +99,40 @@ func (r *CronJob) Default() {
 		r.Spec.ConcurrencyPolicy = AllowConcurrent
 	}
 
+	if r.Spec.ScheduleChangePolicy == "" {
+		r.Spec.ScheduleChangePolicy = HonorScheduleChange
+	}
+
+	if r.Spec.ExpirePolicy == "" {
+		r.Spec.ExpirePolicy = SuspendOnExpiry
+	}
+
+	if r.Spec.RunCountPolicy == "" {
+		r.Spec.RunCountPolicy = CountSucceededRuns
+	}
+
+	if r.Spec.CompletionPolicy == "" {
+		r.Spec.CompletionPolicy = KeepOnCompletion
+	}
+
+	if r.Spec.HTTPAction != nil && r.Spec.HTTPAction.Method == "" {
+		r.Spec.HTTPAction.Method = http.MethodPost
+	}
+
 	if r.Spec.Suspend == nil {
 		r.Spec.Suspend = new(bool)
 	}
+	if r.Spec.UpcomingRunsLimit == nil {
+		r.Spec.UpcomingRunsLimit = new(int32)
+		*r.Spec.UpcomingRunsLimit = DefaultUpcomingRunsLimit
+	}
 	if r.Spec.SuccessfulJobHistoryLimit == nil {
 		r.Spec.SuccessfulJobHistoryLimit = new(int32)
-		*r.Spec.SuccessfulJobHistoryLimit = 3
+		*r.Spec.SuccessfulJobHistoryLimit = DefaultSuccessfulJobsHistoryLimit
 	}
 	if r.Spec.FailedJobsHistoryLimit == nil {
 		r.Spec.FailedJobsHistoryLimit = new(int32)
-		*r.Spec.FailedJobsHistoryLimit = 1
+		*r.Spec.FailedJobsHistoryLimit = DefaultFailedJobsHistoryLimit
 	}
 
 }
@@ -70,6 +142,278 @@ func (r *CronJob) Default() {
 
 var _ webhook.Validator = &CronJob{}
 
+/*
+cronJobValidator serves the validating webhook. It runs the static checks of the
+webhook.Validator implementation below, makes sure the author may do what the CronJob
+will do on their behalf and, when those pass, renders the Job for the next tick and
+submits it as a server-side dry-run. That way quota, LimitRange, PodSecurity
+admission and any other admission webhooks in the cluster get to reject a bad job
+template at `kubectl apply` time rather than when the first run is due. Updates that only
+suspend or resume the CronJob skip all of that.
+*/
+// +kubebuilder:object:generate=false
+type cronJobValidator struct {
+	workloadValidator
+}
+
+/*
+workloadValidator holds what the validating webhooks of CronJobs and ScheduledJobs share:
+the namespaces they are responsible for, the CronJobPolicies, the SubjectAccessReviews and
+the dry-run of the Job. Either kind makes the controller run the same Jobs, so a tenant
+mustn't get past the checks of one by using the other.
+*/
+// +kubebuilder:object:generate=false
+type workloadValidator struct {
+	Client          client.Client
+	Scheme          *runtime.Scheme
+	WatchNamespaces []string
+	decoder         *admission.Decoder
+}
+
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=create
+//+kubebuilder:rbac:groups=core,resources=pods,verbs=create
+
+var _ admission.Handler = &cronJobValidator{}
+
+// Handle implements admission.Handler
+func (v *cronJobValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if !v.watches(req.Namespace) {
+		return admission.Allowed("namespace is not watched by this instance")
+	}
+
+	cronJob := &CronJob{}
+	var warnings []string
+
+	switch req.Operation {
+	case admissionv1.Create:
+		if err := v.decoder.Decode(req, cronJob); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if err := cronJob.ValidateCreate(); err != nil {
+			return denied(err)
+		}
+		warnings = scheduleWarnings(cronJob.Spec.Schedule, time.Now())
+	case admissionv1.Update:
+		oldCronJob := &CronJob{}
+		if err := v.decoder.DecodeRaw(req.Object, cronJob); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if err := v.decoder.DecodeRaw(req.OldObject, oldCronJob); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if err := cronJob.ValidateUpdate(oldCronJob); err != nil {
+			return denied(err)
+		}
+		warnings = cronJob.warningsForUpdate(oldCronJob)
+		if cronJob.Spec.Schedule != oldCronJob.Spec.Schedule {
+			warnings = append(warnings, scheduleWarnings(cronJob.Spec.Schedule, time.Now())...)
+		}
+		// metadata-only edits can't change what the Job looks like, so there is no
+		// need to bother the API server again
+		if apiequality.Semantic.DeepEqual(oldCronJob.Spec, cronJob.Spec) {
+			return admission.Allowed("").WithWarnings(warnings...)
+		}
+		// Suspending and resuming, whether by hand or by the controller itself, has to work
+		// even when the quota is used up or a newer policy rejects the CronJob: suspending
+		// is what one does about a CronJob in trouble.
+		if onlySuspensionChanged(oldCronJob, cronJob) {
+			return admission.Allowed("").WithWarnings(warnings...)
+		}
+	case admissionv1.Delete:
+		// OldObject contains the object being deleted
+		if err := v.decoder.DecodeRaw(req.OldObject, cronJob); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if err := cronJob.ValidateDelete(); err != nil {
+			return denied(err)
+		}
+		return admission.Allowed("")
+	default:
+		return admission.Allowed("")
+	}
+
+	if cronJob.Namespace == "" {
+		cronJob.Namespace = req.Namespace
+	}
+	allErrs, err := v.validateAgainstPolicies(ctx, cronJob.Namespace, cronJob.validateCronJobPolicy)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if len(allErrs) == 0 {
+		if allErrs, err = v.authorize(ctx, req.UserInfo, cronJob); err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+	}
+	if len(allErrs) == 0 {
+		allErrs = v.dryRunJob(ctx, cronJob)
+	}
+	if len(allErrs) > 0 {
+		return denied(apierrors.NewInvalid(
+			schema.GroupKind{Group: "batch.tutorial.kubebuilder.io", Kind: "CronJob"},
+			cronJob.Name, allErrs)).WithWarnings(warnings...)
+	}
+	return admission.Allowed("").WithWarnings(warnings...)
+}
+
+//+kubebuilder:rbac:groups=batch.tutorial.kubebuilder.io,resources=cronjobpolicies,verbs=get;list;watch
+
+// validateAgainstPolicies checks an object with validate against every CronJobPolicy in
+// its namespace.
+func (v *workloadValidator) validateAgainstPolicies(ctx context.Context, namespace string, validate func(*CronJobPolicy) field.ErrorList) (field.ErrorList, error) {
+	var policies CronJobPolicyList
+	if err := v.Client.List(ctx, &policies, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	var allErrs field.ErrorList
+	for i := range policies.Items {
+		allErrs = append(allErrs, validate(&policies.Items[i])...)
+	}
+	return allErrs, nil
+}
+
+func (v *workloadValidator) watches(namespace string) bool {
+	if len(v.WatchNamespaces) == 0 {
+		return true
+	}
+	for _, ns := range v.WatchNamespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// onlySuspensionChanged tells whether the spec of the CronJob differs from the old one in
+// suspend, suspendUntil and suspendReason only.
+func onlySuspensionChanged(old, cronJob *CronJob) bool {
+	spec := cronJob.Spec.DeepCopy()
+	spec.Suspend = old.Spec.Suspend
+	spec.SuspendUntil = old.Spec.SuspendUntil
+	spec.SuspendReason = old.Spec.SuspendReason
+	return apiequality.Semantic.DeepEqual(old.Spec, *spec)
+}
+
+// denied turns a validation error into an admission response, keeping the structured
+// status of API errors so that kubectl can print the individual field errors.
+func denied(err error) admission.Response {
+	var apiStatus apierrors.APIStatus
+	if errors.As(err, &apiStatus) {
+		status := apiStatus.Status()
+		return admission.Response{
+			AdmissionResponse: admissionv1.AdmissionResponse{
+				Allowed: false,
+				Result:  &status,
+			},
+		}
+	}
+	return admission.Denied(err.Error())
+}
+
+/*
+dryRunJob renders the Job that the controller would create for the next tick and submits
+it, together with a Pod built from its template, as a server-side dry-run. Most of the
+interesting admission (LimitRange, PodSecurity, pod quota) only applies to Pods, which
+the Job controller creates much later, so the Pod is dry-run as well.
+*/
+func (v *cronJobValidator) dryRunJob(ctx context.Context, cronJob *CronJob) field.ErrorList {
+	fldPath := field.NewPath("spec").Child("jobTemplate")
+
+	// actions don't create anything to try out, and their targets may well not exist yet
+	if cronJob.HasAction() {
+		return nil
+	}
+	// the schedule has already been validated
+	sched, err := cron.ParseStandard(cronJob.Spec.Schedule)
+	if err != nil {
+		return nil
+	}
+	// the Jobs of the matrix entries differ in their environment only, so one of them will do
+	run := cronJob.RunsForMatrix(Run{ScheduledTime: sched.Next(time.Now()), Trigger: ScheduledRun, Attempt: 1})[0]
+
+	// Objects of other kinds have no pods we know of, so there is just the object itself.
+	if cronJob.Spec.ResourceTemplate != nil {
+		fldPath := field.NewPath("spec").Child("resourceTemplate", "object")
+		obj, err := ConstructResourceForCronJobRun(cronJob, run, v.Scheme)
+		if err != nil {
+			return field.ErrorList{field.Invalid(fldPath, "", err.Error())}
+		}
+		obj.SetOwnerReferences(nil)
+		if err := v.Client.Create(ctx, obj, client.DryRunAll); err != nil {
+			return dryRunErrors(fldPath, err)
+		}
+		return nil
+	}
+
+	job, err := ConstructJobForCronJobRun(cronJob, run, v.Scheme)
+	if err != nil {
+		return field.ErrorList{field.InternalError(fldPath, err)}
+	}
+	return v.dryRunJobAndPod(ctx, job, fldPath)
+}
+
+// dryRunJobAndPod dry-runs the Job and a Pod of its template, and reports what gets
+// rejected as errors under fldPath, the job template the Job was made from.
+func (v *workloadValidator) dryRunJobAndPod(ctx context.Context, job *kbatch.Job, fldPath *field.Path) field.ErrorList {
+	// on create the owner doesn't exist yet, so the Job can't point at it
+	job.OwnerReferences = nil
+
+	if err := v.Client.Create(ctx, job, client.DryRunAll); err != nil {
+		return dryRunErrors(fldPath, err)
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: job.Name + "-",
+			Namespace:    job.Namespace,
+			Labels:       job.Spec.Template.Labels,
+			Annotations:  job.Spec.Template.Annotations,
+		},
+		Spec: job.Spec.Template.Spec,
+	}
+	if err := v.Client.Create(ctx, pod, client.DryRunAll); err != nil {
+		return dryRunErrors(fldPath.Child("spec", "template"), err)
+	}
+	return nil
+}
+
+// dryRunErrors maps the error of a dry-run create onto field errors under fldPath.
+func dryRunErrors(fldPath *field.Path, err error) field.ErrorList {
+	var statusErr *apierrors.StatusError
+	switch {
+	case apierrors.IsAlreadyExists(err):
+		// a Job for the next tick is already there, which tells us nothing about the template
+		return nil
+	case meta.IsNoMatchError(err):
+		// only possible for resource templates: nobody could ever create the object
+		return field.ErrorList{field.Invalid(fldPath, "", err.Error())}
+	case !errors.As(err, &statusErr):
+		// the dry-run is a best effort; don't block admission when we can't reach the API server
+		cronjoblog.Error(err, "unable to dry-run job template")
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	if details := statusErr.ErrStatus.Details; details != nil {
+		for _, cause := range details.Causes {
+			if cause.Field == "" {
+				continue
+			}
+			errType := field.ErrorType(cause.Type)
+			allErrs = append(allErrs, &field.Error{
+				Type:     errType,
+				Field:    fldPath.String() + "." + cause.Field,
+				BadValue: field.OmitValueType{},
+				Detail:   strings.TrimPrefix(cause.Message, errType.String()+": "),
+			})
+		}
+	}
+	if len(allErrs) == 0 {
+		allErrs = append(allErrs, field.Forbidden(fldPath, statusErr.ErrStatus.Message))
+	}
+	return allErrs
+}
+
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type
 func (r *CronJob) ValidateCreate() error {
 	cronjoblog.Info("validate create", "name", r.Name)
@@ -81,7 +425,13 @@ func (r *CronJob) ValidateCreate() error {
 func (r *CronJob) ValidateUpdate(old runtime.Object) error {
 	cronjoblog.Info("validate update", "name", r.Name)
 
-	return r.validateCronJob()
+	oldCronJob, ok := old.(*CronJob)
+	if !ok {
+		return fmt.Errorf("expected a CronJob but got a %T", old)
+	}
+	allErrs := r.cronJobErrors()
+	allErrs = append(allErrs, r.validateCronJobUpdate(oldCronJob)...)
+	return r.invalidError(allErrs)
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
@@ -94,6 +444,11 @@ func (r *CronJob) ValidateDelete() error {
 
 // Add the validation logic
 func (r *CronJob) validateCronJob() error {
+	return r.invalidError(r.cronJobErrors())
+}
+
+// cronJobErrors collects the checks that apply to every CronJob, old or new.
+func (r *CronJob) cronJobErrors() field.ErrorList {
 	var allErrs field.ErrorList
 	if err := r.validateCronJobName(); err != nil {
 		allErrs = append(allErrs, err)
@@ -101,6 +456,19 @@ func (r *CronJob) validateCronJob() error {
 	if err := r.validateCronJobSpec(); err != nil {
 		allErrs = append(allErrs, err)
 	}
+	if err := r.validateActiveRange(); err != nil {
+		allErrs = append(allErrs, err)
+	}
+	allErrs = append(allErrs, r.validateMatrixNames()...)
+	allErrs = append(allErrs, r.validateTemplates()...)
+	allErrs = append(allErrs, r.validateNotifications()...)
+	if err := r.validateDurations(); err != nil {
+		allErrs = append(allErrs, err)
+	}
+	return allErrs
+}
+
+func (r *CronJob) invalidError(allErrs field.ErrorList) error {
 	if len(allErrs) == 0 {
 		return nil
 	}
@@ -110,6 +478,219 @@ func (r *CronJob) validateCronJob() error {
 		r.Name, allErrs)
 }
 
+/*
+Some rules only make sense against the object being replaced. The identity of the Jobs
+we create (their selector) can't change under our feet, and switching to Replace while
+runs are in flight would kill them, so we want the user to say they mean it.
+*/
+func (r *CronJob) validateCronJobUpdate(old *CronJob) field.ErrorList {
+	var allErrs field.ErrorList
+	jobSpecPath := field.NewPath("spec").Child("jobTemplate", "spec")
+
+	allErrs = append(allErrs, apivalidation.ValidateImmutableField(
+		r.Spec.JobTemplate.Spec.Selector, old.Spec.JobTemplate.Spec.Selector, jobSpecPath.Child("selector"))...)
+	allErrs = append(allErrs, apivalidation.ValidateImmutableField(
+		r.Spec.JobTemplate.Spec.ManualSelector, old.Spec.JobTemplate.Spec.ManualSelector, jobSpecPath.Child("manualSelector"))...)
+
+	// the confirmation is for this switch only, so it has to come with it
+	confirmed := r.Annotations[ConfirmReplaceAnnotation] == "true" && old.Annotations[ConfirmReplaceAnnotation] != "true"
+	if r.Spec.ConcurrencyPolicy == ReplaceConcurrent && old.Spec.ConcurrencyPolicy != ReplaceConcurrent &&
+		len(old.Status.Active) > 0 && !confirmed {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec").Child("concurrencyPolicy"),
+			fmt.Sprintf("switching to %s would cancel %d active job(s); add the %s annotation with \"true\" in the same update to confirm",
+				ReplaceConcurrent, len(old.Status.Active), ConfirmReplaceAnnotation)))
+	}
+	return allErrs
+}
+
+/*
+validateCronJobPolicy reports every way in which the CronJob exceeds the limits of the
+policy. Each error names the policy, so that tenants know whom to talk to.
+*/
+func (r *CronJob) validateCronJobPolicy(policy *CronJobPolicy) field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+	forbidden := func(fldPath *field.Path, format string, args ...interface{}) {
+		allErrs = append(allErrs, policyForbidden(policy, fldPath, format, args...))
+	}
+
+	if minInterval := policy.Spec.MinInterval; minInterval != nil {
+		// an unparseable schedule is reported by validateCronJobSpec
+		if interval, err := ShortestInterval(r.Spec.Schedule, time.Now(), minInterval.Duration); err == nil && interval != 0 && interval < minInterval.Duration {
+			forbidden(specPath.Child("schedule"), "ticks may be %s apart, the minimum interval is %s",
+				interval, minInterval.Duration)
+		}
+	}
+
+	if len(policy.Spec.AllowedConcurrencyPolicies) > 0 {
+		allowed := false
+		for _, p := range policy.Spec.AllowedConcurrencyPolicies {
+			if p == r.Spec.ConcurrencyPolicy {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			forbidden(specPath.Child("concurrencyPolicy"), "%q is not one of the allowed policies %v",
+				r.Spec.ConcurrencyPolicy, policy.Spec.AllowedConcurrencyPolicies)
+		}
+	}
+
+	if exceeds(r.Spec.SuccessfulJobHistoryLimit, policy.Spec.MaxSuccessfulJobsHistoryLimit) {
+		forbidden(specPath.Child("successfulJobHistoryLimit"), "must be no more than %d",
+			*policy.Spec.MaxSuccessfulJobsHistoryLimit)
+	}
+	if exceeds(r.Spec.FailedJobsHistoryLimit, policy.Spec.MaxFailedJobsHistoryLimit) {
+		forbidden(specPath.Child("failedJobHistoryLimit"), "must be no more than %d",
+			*policy.Spec.MaxFailedJobsHistoryLimit)
+	}
+
+	// Forbid and Replace never run more than one Job at a time
+	if policy.Spec.MaxConcurrentRuns != nil && r.Spec.ConcurrencyPolicy == AllowConcurrent {
+		if r.Spec.MaxConcurrentRuns == nil {
+			forbidden(specPath.Child("maxConcurrentRuns"), "must be set to no more than %d when concurrent runs are allowed",
+				*policy.Spec.MaxConcurrentRuns)
+		} else if exceeds(r.Spec.MaxConcurrentRuns, policy.Spec.MaxConcurrentRuns) {
+			forbidden(specPath.Child("maxConcurrentRuns"), "must be no more than %d", *policy.Spec.MaxConcurrentRuns)
+		}
+	}
+
+	allErrs = append(allErrs, validateImagePolicy(policy, &r.Spec.JobTemplate.Spec.Template.Spec,
+		specPath.Child("jobTemplate", "spec", "template", "spec"))...)
+
+	if len(policy.Spec.AllowedHosts) > 0 {
+		checkURL := func(rawURL string, fldPath *field.Path) {
+			if u, err := url.Parse(rawURL); err == nil && !hostAllowed(u.Hostname(), policy.Spec.AllowedHosts) {
+				forbidden(fldPath, "host %q is not allowed", u.Hostname())
+			}
+		}
+		if action := r.Spec.HTTPAction; action != nil {
+			checkURL(action.URL, specPath.Child("httpAction", "url"))
+		}
+		for i, n := range r.Spec.Notifications {
+			fldPath := specPath.Child("notifications").Index(i)
+			switch {
+			case n.Webhook != nil:
+				checkURL(n.Webhook.URL, fldPath.Child("webhook", "url"))
+			case n.CloudEvents != nil:
+				checkURL(n.CloudEvents.URL, fldPath.Child("cloudEvents", "url"))
+			case n.Email != nil:
+				host, _, err := net.SplitHostPort(n.Email.SMTPServer)
+				if err != nil {
+					host = n.Email.SMTPServer
+				}
+				if !hostAllowed(host, policy.Spec.AllowedHosts) {
+					forbidden(fldPath.Child("email", "smtpServer"), "host %q is not allowed", host)
+				}
+			}
+		}
+	}
+
+	return allErrs
+}
+
+// policyForbidden reports a field the policy forbids. The error names the policy, so that
+// tenants know whom to talk to.
+func policyForbidden(policy *CronJobPolicy, fldPath *field.Path, format string, args ...interface{}) *field.Error {
+	return field.Forbidden(fldPath, fmt.Sprintf("CronJobPolicy %q: ", policy.Name)+fmt.Sprintf(format, args...))
+}
+
+// validateImagePolicy checks the images of the containers of a pod template against the
+// registries and images the policy allows.
+func validateImagePolicy(policy *CronJobPolicy, podSpec *corev1.PodSpec, podSpecPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	checkImages := func(containers []corev1.Container, fldPath *field.Path) {
+		for i, c := range containers {
+			if !imageAllowed(c.Image, policy.Spec.AllowedRegistries, policy.Spec.AllowedImages) {
+				allErrs = append(allErrs, policyForbidden(policy, fldPath.Index(i).Child("image"), "image %q is not allowed", c.Image))
+			}
+		}
+	}
+	checkImages(podSpec.InitContainers, podSpecPath.Child("initContainers"))
+	checkImages(podSpec.Containers, podSpecPath.Child("containers"))
+	return allErrs
+}
+
+func exceeds(value, max *int32) bool {
+	return value != nil && max != nil && *value > *max
+}
+
+// hostAllowed checks a host name against the hosts and patterns of a CronJobPolicy.
+func hostAllowed(host string, allowedHosts []string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range allowedHosts {
+		if ok, _ := path.Match(strings.ToLower(pattern), host); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// imageAllowed checks an image against the registries and patterns of a CronJobPolicy.
+func imageAllowed(image string, allowedRegistries, allowedImages []string) bool {
+	if len(allowedRegistries) > 0 {
+		registry := imageRegistry(image)
+		allowed := false
+		for _, r := range allowedRegistries {
+			if r == registry {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	if len(allowedImages) > 0 {
+		for _, pattern := range allowedImages {
+			if ok, _ := path.Match(pattern, image); ok {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// imageRegistry returns the registry host of an image reference, following the same
+// rules as the container runtimes: the first path component is a registry only if it
+// looks like a host name, and index.docker.io is another name for docker.io.
+func imageRegistry(image string) string {
+	i := strings.IndexRune(image, '/')
+	if i == -1 {
+		return "docker.io"
+	}
+	host := image[:i]
+	if host == "index.docker.io" {
+		return "docker.io"
+	}
+	if strings.ContainsAny(host, ".:") || host == "localhost" {
+		return host
+	}
+	return "docker.io"
+}
+
+// warningsForUpdate returns admission warnings for changes that are allowed, but
+// whose consequences the user might not expect.
+func (r *CronJob) warningsForUpdate(old *CronJob) []string {
+	var warnings []string
+	if lowered(r.Spec.SuccessfulJobHistoryLimit, old.Spec.SuccessfulJobHistoryLimit) {
+		warnings = append(warnings, fmt.Sprintf(
+			"spec.successfulJobHistoryLimit lowered from %d to %d: older successful Jobs will be deleted on the next reconcile",
+			*old.Spec.SuccessfulJobHistoryLimit, *r.Spec.SuccessfulJobHistoryLimit))
+	}
+	if lowered(r.Spec.FailedJobsHistoryLimit, old.Spec.FailedJobsHistoryLimit) {
+		warnings = append(warnings, fmt.Sprintf(
+			"spec.failedJobHistoryLimit lowered from %d to %d: older failed Jobs will be deleted on the next reconcile",
+			*old.Spec.FailedJobsHistoryLimit, *r.Spec.FailedJobsHistoryLimit))
+	}
+	return warnings
+}
+
+func lowered(limit, oldLimit *int32) bool {
+	return limit != nil && oldLimit != nil && *limit < *oldLimit
+}
+
 // implement validation functions
 func (r *CronJob) validateCronJobSpec() *field.Error {
 	// The field helpers from the kubernetes API machinery help us return nicely
@@ -119,6 +700,84 @@ func (r *CronJob) validateCronJobSpec() *field.Error {
 		field.NewPath("spec").Child("schedule"))
 }
 
+// The active range must not be empty.
+func (r *CronJob) validateActiveRange() *field.Error {
+	if r.Spec.ActiveFrom == nil || r.Spec.ActiveUntil == nil || r.Spec.ActiveFrom.Before(r.Spec.ActiveUntil) {
+		return nil
+	}
+	return field.Invalid(field.NewPath("spec").Child("activeUntil"), r.Spec.ActiveUntil.Format(time.RFC3339),
+		"must be after spec.activeFrom")
+}
+
+// A CronJob runs either Jobs, objects of some other kind or an action; the latter two have
+// no pods to pass the parameters of a matrix entry to.
+func (r *CronJob) validateTemplates() field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+	var set []string
+	if !apiequality.Semantic.DeepEqual(r.Spec.JobTemplate, kbatch.JobTemplateSpec{}) {
+		set = append(set, "jobTemplate")
+	}
+	if r.Spec.ResourceTemplate != nil {
+		set = append(set, "resourceTemplate")
+	}
+	if r.Spec.ScaleAction != nil {
+		set = append(set, "scaleAction")
+	}
+	if r.Spec.HTTPAction != nil {
+		set = append(set, "httpAction")
+	}
+	switch {
+	case len(set) == 0:
+		allErrs = append(allErrs, field.Required(specPath.Child("jobTemplate"),
+			"one of jobTemplate, resourceTemplate, scaleAction and httpAction is required"))
+	case len(set) > 1:
+		allErrs = append(allErrs, field.Forbidden(specPath.Child(set[1]), "may not be set together with "+set[0]))
+	}
+
+	if r.Spec.ResourceTemplate != nil {
+		if _, err := r.Spec.ResourceTemplate.GroupVersionKind(); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("resourceTemplate", "object"), "", err.Error()))
+		}
+	}
+	if r.Spec.HTTPAction != nil {
+		if u, err := url.ParseRequestURI(r.Spec.HTTPAction.URL); err != nil || u.Host == "" {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("httpAction", "url"), r.Spec.HTTPAction.URL, "must be an absolute URL"))
+		}
+	}
+	if len(r.Spec.Matrix) > 0 && (r.Spec.ResourceTemplate != nil || r.HasAction()) {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("matrix"), "is only supported with jobTemplate"))
+	}
+	return allErrs
+}
+
+// A run can't reach its maximum duration before it's overdue.
+func (r *CronJob) validateDurations() *field.Error {
+	if r.Spec.ExpectedDuration == nil || r.Spec.MaxDuration == nil || r.Spec.MaxDuration.Duration >= r.Spec.ExpectedDuration.Duration {
+		return nil
+	}
+	return field.Invalid(field.NewPath("spec").Child("maxDuration"), r.Spec.MaxDuration.Duration.String(),
+		"must not be shorter than spec.expectedDuration")
+}
+
+// Each notification goes to exactly one sink.
+func (r *CronJob) validateNotifications() field.ErrorList {
+	var allErrs field.ErrorList
+	for i, n := range r.Spec.Notifications {
+		sinks := 0
+		for _, set := range []bool{n.Webhook != nil, n.Email != nil, n.CloudEvents != nil} {
+			if set {
+				sinks++
+			}
+		}
+		if sinks != 1 {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec").Child("notifications").Index(i), n.Name,
+				"must have exactly one of webhook, email and cloudEvents"))
+		}
+	}
+	return allErrs
+}
+
 // We'll need to validate if the cron schedule is well-formatted.
 func validateScheduleFormat(schedule string, fldPath *field.Path) *field.Error {
 	if _, err := cron.ParseStandard(schedule); err != nil {
@@ -145,3 +804,16 @@ func (r *CronJob) validateCronJobName() *field.Error {
 	}
 	return nil
 }
+
+// The Jobs of matrix entries have the entry name appended on top, which has to fit as well.
+func (r *CronJob) validateMatrixNames() field.ErrorList {
+	var allErrs field.ErrorList
+	for i, entry := range r.Spec.Matrix {
+		if len(r.Name)+1+len(entry.Name) > validationutils.DNS1035LabelMaxLength-11 {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec").Child("matrix").Index(i).Child("name"), entry.Name,
+				fmt.Sprintf("must be no more than %d characters with a CronJob name of %d characters",
+					validationutils.DNS1035LabelMaxLength-11-1-len(r.Name), len(r.Name))))
+		}
+	}
+	return allErrs
+}
@@ -0,0 +1,387 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestDryRunErrors(t *testing.T) {
+	fldPath := field.NewPath("spec", "jobTemplate")
+	jobs := schema.GroupResource{Group: "batch", Resource: "jobs"}
+	invalid := apierrors.NewInvalid(schema.GroupKind{Group: "batch", Kind: "Job"}, "job", field.ErrorList{
+		field.Required(field.NewPath("spec", "template", "spec", "containers"), "must have a container"),
+		field.Invalid(field.NewPath("spec", "parallelism"), -1, "must be non-negative"),
+	})
+
+	for name, tc := range map[string]struct {
+		err  error
+		want field.ErrorList
+	}{
+		"already exists": {err: apierrors.NewAlreadyExists(jobs, "job")},
+		"unreachable":    {err: errors.New("connection refused")},
+		"no match": {
+			err:  &meta.NoKindMatchError{GroupKind: schema.GroupKind{Group: "example.com", Kind: "Widget"}},
+			want: field.ErrorList{field.Invalid(fldPath, "", "")},
+		},
+		"invalid": {
+			err: invalid,
+			want: field.ErrorList{
+				{Type: field.ErrorTypeRequired, Field: "spec.jobTemplate.spec.template.spec.containers", Detail: "must have a container"},
+				{Type: field.ErrorTypeInvalid, Field: "spec.jobTemplate.spec.parallelism"},
+			},
+		},
+		"forbidden": {
+			err:  apierrors.NewForbidden(jobs, "job", errors.New("exceeded quota")),
+			want: field.ErrorList{field.Forbidden(fldPath, "")},
+		},
+	} {
+		got := dryRunErrors(fldPath, tc.err)
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: dryRunErrors() = %v, want %d errors", name, got, len(tc.want))
+			continue
+		}
+		for i, want := range tc.want {
+			if got[i].Type != want.Type || got[i].Field != want.Field || (want.Detail != "" && got[i].Detail != want.Detail) {
+				t.Errorf("%s: error %d = %v, want %s on %s", name, i, got[i], want.Type, want.Field)
+			}
+		}
+	}
+}
+
+func TestValidateCronJobUpdate(t *testing.T) {
+	active := []corev1.ObjectReference{{Name: "job"}}
+	confirmed := map[string]string{ConfirmReplaceAnnotation: "true"}
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "report"}}
+	cronJob := func(policy ConcurrencyPolicy, annotations map[string]string, active []corev1.ObjectReference) *CronJob {
+		return &CronJob{
+			ObjectMeta: metav1.ObjectMeta{Annotations: annotations},
+			Spec:       CronJobSpec{ConcurrencyPolicy: policy},
+			Status:     CronJobStatus{Active: active},
+		}
+	}
+
+	for name, tc := range map[string]struct {
+		old, new *CronJob
+		wantErrs int
+	}{
+		"nothing running": {
+			old: cronJob(AllowConcurrent, nil, nil), new: cronJob(ReplaceConcurrent, nil, nil),
+		},
+		"unconfirmed": {
+			old: cronJob(AllowConcurrent, nil, active), new: cronJob(ReplaceConcurrent, nil, active), wantErrs: 1,
+		},
+		"confirmed": {
+			old: cronJob(AllowConcurrent, nil, active), new: cronJob(ReplaceConcurrent, confirmed, active),
+		},
+		"confirmed by an earlier update": {
+			old: cronJob(ForbidConcurrent, confirmed, active), new: cronJob(ReplaceConcurrent, confirmed, active), wantErrs: 1,
+		},
+		"already Replace": {
+			old: cronJob(ReplaceConcurrent, nil, active), new: cronJob(ReplaceConcurrent, nil, active),
+		},
+		"selector changed": {
+			old: cronJob(AllowConcurrent, nil, nil),
+			new: func() *CronJob {
+				c := cronJob(AllowConcurrent, nil, nil)
+				c.Spec.JobTemplate = kbatch.JobTemplateSpec{Spec: kbatch.JobSpec{Selector: selector}}
+				return c
+			}(),
+			wantErrs: 1,
+		},
+	} {
+		if errs := tc.new.validateCronJobUpdate(tc.old); len(errs) != tc.wantErrs {
+			t.Errorf("%s: validateCronJobUpdate() = %v, want %d errors", name, errs, tc.wantErrs)
+		}
+	}
+}
+
+func TestCronJobValidatorSuspension(t *testing.T) {
+	newCronJob := func(mutate func(*CronJobSpec)) *CronJob {
+		suspend := false
+		cronJob := &CronJob{
+			TypeMeta:   metav1.TypeMeta{APIVersion: GroupVersion.String(), Kind: "CronJob"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "report"},
+			Spec: CronJobSpec{
+				Schedule:          "0 * * * *",
+				ConcurrencyPolicy: AllowConcurrent,
+				Suspend:           &suspend,
+				JobTemplate: kbatch.JobTemplateSpec{Spec: kbatch.JobSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers:    []corev1.Container{{Name: "report", Image: "busybox"}},
+				}}}},
+			},
+		}
+		if mutate != nil {
+			mutate(&cronJob.Spec)
+		}
+		return cronJob
+	}
+	// the CronJob got in before the policy, and the quota is used up
+	policy := &CronJobPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "registries"},
+		Spec:       CronJobPolicySpec{AllowedRegistries: []string{"registry.example.com"}},
+	}
+	quotaExceeded := apierrors.NewForbidden(schema.GroupResource{Group: "batch", Resource: "jobs"}, "report",
+		errors.New("exceeded quota: compute-resources"))
+
+	for name, tc := range map[string]struct {
+		mutate  func(*CronJobSpec)
+		allowed bool
+	}{
+		"suspend": {
+			mutate:  func(spec *CronJobSpec) { suspend := true; spec.Suspend = &suspend },
+			allowed: true,
+		},
+		"suspend until": {
+			mutate: func(spec *CronJobSpec) {
+				spec.SuspendUntil = &metav1.Time{Time: time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)}
+				spec.SuspendReason = "quota used up"
+			},
+			allowed: true,
+		},
+		"schedule": {
+			mutate: func(spec *CronJobSpec) { spec.Schedule = "30 * * * *" },
+		},
+		"suspend and schedule": {
+			mutate: func(spec *CronJobSpec) { suspend := true; spec.Suspend, spec.Schedule = &suspend, "30 * * * *" },
+		},
+	} {
+		for _, withPolicy := range []bool{false, true} {
+			var objs []client.Object
+			if withPolicy {
+				objs = append(objs, policy)
+			}
+			base, c := newTestWorkloadValidator(t, func(*authorizationv1.ResourceAttributes) bool { return true }, objs...)
+			c.dryRunErr = quotaExceeded
+			v := &cronJobValidator{base}
+
+			old := newCronJob(nil)
+			resp := v.Handle(context.Background(), admissionRequest(t, admissionv1.Update, "team-a", newCronJob(tc.mutate), old))
+			if resp.Allowed != tc.allowed {
+				t.Errorf("%s (policy %t): allowed = %t, want %t: %v", name, withPolicy, resp.Allowed, tc.allowed, resp.Result)
+			}
+		}
+	}
+}
+
+func TestCronJobValidatorWatchNamespaces(t *testing.T) {
+	// a CronJob no watching instance would admit
+	cronJob := &CronJob{
+		TypeMeta:   metav1.TypeMeta{APIVersion: GroupVersion.String(), Kind: "CronJob"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "report"},
+		Spec:       CronJobSpec{Schedule: "every hour"},
+	}
+	for name, tc := range map[string]struct {
+		namespaces []string
+		watched    bool
+	}{
+		"all namespaces":     {nil, true},
+		"watched namespace":  {[]string{"team-b", "team-a"}, true},
+		"other namespaces":   {[]string{"team-b", "team-c"}, false},
+		"namespace prefixed": {[]string{"team"}, false},
+	} {
+		base, c := newTestWorkloadValidator(t, func(*authorizationv1.ResourceAttributes) bool { return true })
+		base.WatchNamespaces = tc.namespaces
+		if got := base.watches("team-a"); got != tc.watched {
+			t.Errorf("%s: watches() = %t, want %t", name, got, tc.watched)
+		}
+		v := &cronJobValidator{base}
+		resp := v.Handle(context.Background(), admissionRequest(t, admissionv1.Create, "team-a", cronJob, nil))
+		if resp.Allowed == tc.watched {
+			t.Errorf("%s: allowed = %t, want %t", name, resp.Allowed, !tc.watched)
+		}
+		if !tc.watched && len(c.reviews) != 0 {
+			t.Errorf("%s: %d SubjectAccessReviews, want none", name, len(c.reviews))
+		}
+	}
+}
+
+func TestWarningsForUpdate(t *testing.T) {
+	limits := func(successful, failed int32) *CronJob {
+		return &CronJob{Spec: CronJobSpec{SuccessfulJobHistoryLimit: &successful, FailedJobsHistoryLimit: &failed}}
+	}
+	for name, tc := range map[string]struct {
+		old, new *CronJob
+		want     int
+	}{
+		"unchanged":    {limits(3, 1), limits(3, 1), 0},
+		"raised":       {limits(3, 1), limits(5, 2), 0},
+		"one lowered":  {limits(3, 1), limits(1, 1), 1},
+		"both lowered": {limits(3, 2), limits(1, 0), 2},
+		"unset":        {&CronJob{}, limits(0, 0), 0},
+	} {
+		if got := tc.new.warningsForUpdate(tc.old); len(got) != tc.want {
+			t.Errorf("%s: warningsForUpdate() = %q, want %d warnings", name, got, tc.want)
+		}
+	}
+}
+
+func TestImageRegistry(t *testing.T) {
+	for image, want := range map[string]string{
+		"busybox":                            "docker.io",
+		"busybox:1.36":                       "docker.io",
+		"busybox@sha256:0123456789abcdef":    "docker.io",
+		"library/busybox":                    "docker.io",
+		"docker.io/library/busybox":          "docker.io",
+		"index.docker.io/library/busybox":    "docker.io",
+		"registry.example.com/team/app:1.0":  "registry.example.com",
+		"registry.example.com:5000/team/app": "registry.example.com:5000",
+		"registry:5000/app@sha256:0123":      "registry:5000",
+		"localhost/app":                      "localhost",
+		"localhost:5000/app":                 "localhost:5000",
+	} {
+		if got := imageRegistry(image); got != want {
+			t.Errorf("imageRegistry(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
+
+func TestImageAllowed(t *testing.T) {
+	registries := []string{"registry.example.com", "docker.io"}
+	images := []string{"registry.example.com/team/*", "busybox*"}
+	for name, tc := range map[string]struct {
+		image              string
+		registries, images []string
+		want               bool
+	}{
+		"no restrictions":        {image: "anything/at:all", want: true},
+		"allowed registry":       {image: "registry.example.com/other/app", registries: registries, want: true},
+		"implicit docker.io":     {image: "busybox", registries: registries, want: true},
+		"other registry":         {image: "quay.io/team/app", registries: registries},
+		"registry with port":     {image: "registry.example.com:5000/team/app", registries: registries},
+		"matching image":         {image: "registry.example.com/team/app:1.0", images: images, want: true},
+		"matching with a digest": {image: "registry.example.com/team/app@sha256:0123", images: images, want: true},
+		"nested path":            {image: "registry.example.com/team/sub/app", images: images},
+		"both have to match":     {image: "registry.example.com/other/app", registries: registries, images: images},
+		"both match":             {image: "busybox:1.36", registries: registries, images: images, want: true},
+	} {
+		if got := imageAllowed(tc.image, tc.registries, tc.images); got != tc.want {
+			t.Errorf("%s: imageAllowed(%q) = %t, want %t", name, tc.image, got, tc.want)
+		}
+	}
+}
+
+func TestValidateCronJobPolicy(t *testing.T) {
+	int32p := func(i int32) *int32 { return &i }
+	cronJob := func(mutate func(*CronJobSpec)) *CronJob {
+		c := &CronJob{Spec: CronJobSpec{
+			Schedule:                  "0 * * * *",
+			ConcurrencyPolicy:         ForbidConcurrent,
+			SuccessfulJobHistoryLimit: int32p(3),
+			FailedJobsHistoryLimit:    int32p(1),
+			JobTemplate: kbatch.JobTemplateSpec{Spec: kbatch.JobSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: "init", Image: "registry.example.com/init"}},
+				Containers:     []corev1.Container{{Name: "main", Image: "registry.example.com/app"}},
+			}}}},
+		}}
+		if mutate != nil {
+			mutate(&c.Spec)
+		}
+		return c
+	}
+	policy := &CronJobPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "limits"},
+		Spec: CronJobPolicySpec{
+			MinInterval:                   &metav1.Duration{Duration: 30 * time.Minute},
+			AllowedConcurrencyPolicies:    []ConcurrencyPolicy{ForbidConcurrent, AllowConcurrent},
+			MaxSuccessfulJobsHistoryLimit: int32p(5),
+			MaxFailedJobsHistoryLimit:     int32p(2),
+			MaxConcurrentRuns:             int32p(2),
+			AllowedRegistries:             []string{"registry.example.com"},
+			AllowedHosts:                  []string{"api.example.com", "*.internal"},
+		},
+	}
+
+	for name, tc := range map[string]struct {
+		cronJob *CronJob
+		want    []string
+	}{
+		"compliant":    {cronJob: cronJob(nil)},
+		"too often":    {cronJob: cronJob(func(s *CronJobSpec) { s.Schedule = "*/10 * * * *" }), want: []string{"spec.schedule"}},
+		"bad schedule": {cronJob: cronJob(func(s *CronJobSpec) { s.Schedule = "every hour" })},
+		"replace":      {cronJob: cronJob(func(s *CronJobSpec) { s.ConcurrencyPolicy = ReplaceConcurrent }), want: []string{"spec.concurrencyPolicy"}},
+		"long histories": {
+			cronJob: cronJob(func(s *CronJobSpec) { s.SuccessfulJobHistoryLimit, s.FailedJobsHistoryLimit = int32p(10), int32p(10) }),
+			want:    []string{"spec.successfulJobHistoryLimit", "spec.failedJobHistoryLimit"},
+		},
+		"unbounded concurrency": {
+			cronJob: cronJob(func(s *CronJobSpec) { s.ConcurrencyPolicy = AllowConcurrent }),
+			want:    []string{"spec.maxConcurrentRuns"},
+		},
+		"too much concurrency": {
+			cronJob: cronJob(func(s *CronJobSpec) { s.ConcurrencyPolicy, s.MaxConcurrentRuns = AllowConcurrent, int32p(3) }),
+			want:    []string{"spec.maxConcurrentRuns"},
+		},
+		"bounded concurrency": {
+			cronJob: cronJob(func(s *CronJobSpec) { s.ConcurrencyPolicy, s.MaxConcurrentRuns = AllowConcurrent, int32p(2) }),
+		},
+		"foreign images": {
+			cronJob: cronJob(func(s *CronJobSpec) {
+				s.JobTemplate.Spec.Template.Spec.InitContainers[0].Image = "busybox"
+				s.JobTemplate.Spec.Template.Spec.Containers[0].Image = "quay.io/app"
+			}),
+			want: []string{"spec.jobTemplate.spec.template.spec.initContainers[0].image", "spec.jobTemplate.spec.template.spec.containers[0].image"},
+		},
+		"allowed host": {
+			cronJob: cronJob(func(s *CronJobSpec) { s.HTTPAction = &HTTPAction{URL: "https://reports.INTERNAL:8443/refresh"} }),
+		},
+		"foreign host": {
+			cronJob: cronJob(func(s *CronJobSpec) { s.HTTPAction = &HTTPAction{URL: "http://169.254.169.254/latest/meta-data"} }),
+			want:    []string{"spec.httpAction.url"},
+		},
+		"notification hosts": {
+			cronJob: cronJob(func(s *CronJobSpec) {
+				s.Notifications = []Notification{
+					{Name: "slack", Webhook: &WebhookSink{URL: "https://hooks.slack.com/services/T0"}},
+					{Name: "events", CloudEvents: &CloudEventsSink{URL: "http://broker.internal/default"}},
+					{Name: "mail", Email: &EmailSink{SMTPServer: "smtp.attacker.example:587"}},
+				}
+			}),
+			want: []string{"spec.notifications[0].webhook.url", "spec.notifications[2].email.smtpServer"},
+		},
+	} {
+		errs := tc.cronJob.validateCronJobPolicy(policy)
+		if len(errs) != len(tc.want) {
+			t.Errorf("%s: validateCronJobPolicy() = %v, want errors on %v", name, errs, tc.want)
+			continue
+		}
+		for i, err := range errs {
+			if err.Field != tc.want[i] || err.Type != field.ErrorTypeForbidden {
+				t.Errorf("%s: error %d = %v, want it forbidden on %s", name, i, err, tc.want[i])
+			}
+		}
+	}
+
+	if errs := cronJob(func(s *CronJobSpec) { s.Schedule = "* * * * *" }).validateCronJobPolicy(&CronJobPolicy{}); len(errs) != 0 {
+		t.Errorf("an empty policy rejected a CronJob: %v", errs)
+	}
+}
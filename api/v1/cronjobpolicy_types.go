@@ -0,0 +1,93 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CronJobPolicySpec defines the limits that CronJobs in the namespace of the policy
+// have to stay within. Unset fields don't restrict anything.
+type CronJobPolicySpec struct {
+	// The minimum time between two consecutive ticks of a CronJob's schedule
+	// +optional
+	MinInterval *metav1.Duration `json:"minInterval,omitempty"`
+
+	// The concurrency policies CronJobs may use. All of them are allowed if empty.
+	// +optional
+	AllowedConcurrencyPolicies []ConcurrencyPolicy `json:"allowedConcurrencyPolicies,omitempty"`
+
+	//+kubebuilder:validation:Minimum=0
+
+	// The largest successfulJobHistoryLimit a CronJob may set
+	// +optional
+	MaxSuccessfulJobsHistoryLimit *int32 `json:"maxSuccessfulJobsHistoryLimit,omitempty"`
+
+	//+kubebuilder:validation:Minimum=0
+
+	// The largest failedJobHistoryLimit a CronJob may set
+	// +optional
+	MaxFailedJobsHistoryLimit *int32 `json:"maxFailedJobsHistoryLimit,omitempty"`
+
+	//+kubebuilder:validation:Minimum=1
+
+	// The largest maxConcurrentRuns a CronJob may set. CronJobs that allow concurrent
+	// runs have to set maxConcurrentRuns when this is set.
+	// +optional
+	MaxConcurrentRuns *int32 `json:"maxConcurrentRuns,omitempty"`
+
+	// The registries images of the job template may be pulled from, e.g. "registry.example.com".
+	// Images without a registry are pulled from "docker.io". All registries are allowed if empty.
+	// +optional
+	AllowedRegistries []string `json:"allowedRegistries,omitempty"`
+
+	// Shell patterns, as understood by path.Match, that images of the job template have to
+	// match, e.g. "registry.example.com/team/*". All images are allowed if empty.
+	// +optional
+	AllowedImages []string `json:"allowedImages,omitempty"`
+
+	// The hosts HTTP actions and notifications may send requests to, SMTP servers included,
+	// or shell patterns of them as understood by path.Match, e.g. "*.example.com". All hosts
+	// are allowed if empty.
+	// +optional
+	AllowedHosts []string `json:"allowedHosts,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// CronJobPolicy is the Schema for the cronjobpolicies API. The CronJob validating
+// webhook enforces every CronJobPolicy in a namespace on the CronJobs in that namespace,
+// and the ScheduledJob one the image limits on the ScheduledJobs.
+type CronJobPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec CronJobPolicySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// CronJobPolicyList contains a list of CronJobPolicy
+type CronJobPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CronJobPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CronJobPolicy{}, &CronJobPolicyList{})
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// ScheduledTimeAnnotation is set on every Job created for a CronJob, so that the
+// controller can reconstitute `LastScheduleTime` from the Jobs themselves.
+const ScheduledTimeAnnotation = "batch.tutorial.kubebuilder.io/scheduled-at"
+
+// ScheduleGenerationAnnotation ties a Job to the schedule that produced it, see
+// `CronJobStatus.ScheduleGeneration`.
+const ScheduleGenerationAnnotation = "batch.tutorial.kubebuilder.io/schedule-generation"
+
+// MatrixEntryLabel is set on the Jobs of a matrix entry, and their pods, to the name of the entry.
+const MatrixEntryLabel = "batch.tutorial.kubebuilder.io/matrix-entry"
+
+// TriggerAnnotation records on every Job of a CronJob how its run came about, see RunTrigger.
+const TriggerAnnotation = "batch.tutorial.kubebuilder.io/trigger"
+
+// AttemptAnnotation records on every Job of a CronJob which attempt at its scheduled time it is.
+const AttemptAnnotation = "batch.tutorial.kubebuilder.io/attempt"
+
+// OverdueAnnotation is set on a job of a CronJob once it's running for longer than the
+// ExpectedDuration or MaxDuration, to the name of the field.
+const OverdueAnnotation = "batch.tutorial.kubebuilder.io/overdue"
+
+// The pod annotations and environment variables set when InjectRunMetadata is true.
+const (
+	CronJobNameAnnotation = "batch.tutorial.kubebuilder.io/cronjob"
+	RunIDAnnotation       = "batch.tutorial.kubebuilder.io/run-id"
+
+	CronJobNameEnv   = "CRONJOB_NAME"
+	ScheduledTimeEnv = "CRONJOB_SCHEDULED_TIME"
+	RunIDEnv         = "CRONJOB_RUN_ID"
+	AttemptEnv       = "CRONJOB_ATTEMPT"
+	TriggerEnv       = "CRONJOB_TRIGGER"
+	MatrixEntryEnv   = "CRONJOB_MATRIX_ENTRY"
+)
+
+// RunTrigger tells how a run of a CronJob came about.
+type RunTrigger string
+
+const (
+	// ScheduledRun was started by the controller, according to the schedule.
+	ScheduledRun RunTrigger = "Scheduled"
+
+	// ManualRun was started by hand, e.g. with `cronctl trigger`.
+	ManualRun RunTrigger = "Manual"
+)
+
+// Run describes a single run of a CronJob, i.e. what the Job for it is built from.
+// +kubebuilder:object:generate=false
+type Run struct {
+	// The nominal time of the run: the slot it processes
+	ScheduledTime time.Time
+
+	// The time the run is started at, which names its Job. Defaults to ScheduledTime.
+	StartTime time.Time
+
+	Trigger RunTrigger
+
+	// 1 for the first run for ScheduledTime, 2 for the next one, and so on
+	Attempt int
+
+	// The matrix entry to run with, if the CronJob has a matrix
+	MatrixEntry *MatrixEntry
+}
+
+// RunsForMatrix returns the run for each entry of the CronJob's matrix, or just the run
+// itself if the CronJob has no matrix.
+func (r *CronJob) RunsForMatrix(run Run) []Run {
+	if len(r.Spec.Matrix) == 0 {
+		return []Run{run}
+	}
+	var runs []Run
+	for i := range r.Spec.Matrix {
+		run.MatrixEntry = &r.Spec.Matrix[i]
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+/*
+ConstructJobForCronJob builds the Job for a given nominal run of the CronJob. We copy
+over the spec from the template and some basic object meta, then set the "ScheduledTime"
+annotation. It lives next to the types rather than in the controller, because the
+validating webhook renders the very same Job to dry-run it against the cluster.
+*/
+func ConstructJobForCronJob(cronJob *CronJob, scheduledTime time.Time, scheme *runtime.Scheme) (*kbatch.Job, error) {
+	return ConstructJobForCronJobRun(cronJob, Run{ScheduledTime: scheduledTime, Trigger: ScheduledRun, Attempt: 1}, scheme)
+}
+
+// ConstructJobForCronJobRun builds the Job for any run of the CronJob, including ones
+// that weren't started by the schedule.
+func ConstructJobForCronJobRun(cronJob *CronJob, run Run, scheme *runtime.Scheme) (*kbatch.Job, error) {
+	startTime := run.StartTime
+	if startTime.IsZero() {
+		startTime = run.ScheduledTime
+	}
+	name := fmt.Sprintf("%s-%d", cronJob.Name, startTime.Unix())
+	if run.MatrixEntry != nil {
+		name += "-" + run.MatrixEntry.Name
+	}
+	job, err := constructJob(cronJob, name, &cronJob.Spec.JobTemplate, run.ScheduledTime, scheme)
+	if err != nil {
+		return nil, err
+	}
+	if entry := run.MatrixEntry; entry != nil {
+		job.Labels[MatrixEntryLabel] = entry.Name
+		if job.Spec.Template.Labels == nil {
+			job.Spec.Template.Labels = make(map[string]string)
+		}
+		job.Spec.Template.Labels[MatrixEntryLabel] = entry.Name
+		setEnv(job.Spec.Template.Spec.InitContainers, entry.Env)
+		setEnv(job.Spec.Template.Spec.Containers, entry.Env)
+	}
+	job.Annotations[ScheduleGenerationAnnotation] = strconv.FormatInt(cronJob.Status.ScheduleGeneration, 10)
+	job.Annotations[TriggerAnnotation] = string(run.Trigger)
+	job.Annotations[AttemptAnnotation] = strconv.Itoa(run.Attempt)
+
+	if cronJob.Spec.InjectRunMetadata != nil && *cronJob.Spec.InjectRunMetadata {
+		injectRunMetadata(&job.Spec.Template, map[string]string{
+			CronJobNameAnnotation:   cronJob.Name,
+			ScheduledTimeAnnotation: run.ScheduledTime.Format(time.RFC3339),
+			RunIDAnnotation:         job.Name,
+			AttemptAnnotation:       strconv.Itoa(run.Attempt),
+			TriggerAnnotation:       string(run.Trigger),
+		})
+		if run.MatrixEntry != nil {
+			setEnv(job.Spec.Template.Spec.InitContainers, []corev1.EnvVar{{Name: MatrixEntryEnv, Value: run.MatrixEntry.Name}})
+			setEnv(job.Spec.Template.Spec.Containers, []corev1.EnvVar{{Name: MatrixEntryEnv, Value: run.MatrixEntry.Name}})
+		}
+	}
+	return job, nil
+}
+
+// setEnv sets the variables in all of the containers, replacing those they already have.
+func setEnv(containers []corev1.Container, env []corev1.EnvVar) {
+	for i := range containers {
+		container := &containers[i]
+		for _, v := range env {
+			replaced := false
+			for j := range container.Env {
+				if container.Env[j].Name == v.Name {
+					container.Env[j] = *v.DeepCopy()
+					replaced = true
+				}
+			}
+			if !replaced {
+				container.Env = append(container.Env, *v.DeepCopy())
+			}
+		}
+	}
+}
+
+// runMetadataEnv maps the run metadata annotations to their environment variables.
+var runMetadataEnv = map[string]string{
+	CronJobNameAnnotation:   CronJobNameEnv,
+	ScheduledTimeAnnotation: ScheduledTimeEnv,
+	RunIDAnnotation:         RunIDEnv,
+	AttemptAnnotation:       AttemptEnv,
+	TriggerAnnotation:       TriggerEnv,
+}
+
+// injectRunMetadata sets the metadata as annotations of the pod, and as environment variables
+// of all of its containers. Variables the template sets itself are left alone.
+func injectRunMetadata(template *corev1.PodTemplateSpec, metadata map[string]string) {
+	if template.Annotations == nil {
+		template.Annotations = make(map[string]string)
+	}
+	for annotation, value := range metadata {
+		template.Annotations[annotation] = value
+	}
+
+	inject := func(containers []corev1.Container) {
+		for i := range containers {
+			container := &containers[i]
+			defined := make(map[string]bool)
+			for _, env := range container.Env {
+				defined[env.Name] = true
+			}
+			// in a fixed order, so that the Job doesn't differ between renderings
+			for _, annotation := range []string{CronJobNameAnnotation, ScheduledTimeAnnotation, RunIDAnnotation, AttemptAnnotation, TriggerAnnotation} {
+				if name := runMetadataEnv[annotation]; !defined[name] {
+					container.Env = append(container.Env, corev1.EnvVar{Name: name, Value: metadata[annotation]})
+				}
+			}
+		}
+	}
+	inject(template.Spec.InitContainers)
+	inject(template.Spec.Containers)
+}
+
+// ConstructJobForScheduledJob builds the one Job of a ScheduledJob, the same way
+// ConstructJobForCronJob does for a run of a CronJob.
+func ConstructJobForScheduledJob(scheduledJob *ScheduledJob, scheme *runtime.Scheme) (*kbatch.Job, error) {
+	runAt := scheduledJob.Spec.RunAt.Time
+	return constructJob(scheduledJob, fmt.Sprintf("%s-%d", scheduledJob.Name, runAt.Unix()),
+		&scheduledJob.Spec.JobTemplate, runAt, scheme)
+}
+
+// constructJob builds a Job from the template, controlled by owner. Callers name Jobs after
+// their nominal start time, so that the same run always gets the same name.
+func constructJob(owner metav1.Object, name string, template *kbatch.JobTemplateSpec, scheduledTime time.Time, scheme *runtime.Scheme) (*kbatch.Job, error) {
+	job := &kbatch.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:      make(map[string]string),
+			Annotations: make(map[string]string),
+			Name:        name,
+			Namespace:   owner.GetNamespace(),
+		},
+		Spec: *template.Spec.DeepCopy(),
+	}
+	for k, v := range template.Annotations {
+		job.Annotations[k] = v
+	}
+	job.Annotations[ScheduledTimeAnnotation] = scheduledTime.Format(time.RFC3339)
+	for k, v := range template.Labels {
+		job.Labels[k] = v
+	}
+	if err := ctrl.SetControllerReference(owner, job, scheme); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
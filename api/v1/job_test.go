@@ -0,0 +1,155 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestConstructJobForCronJobRunInjectsRunMetadata(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	scheduledTime := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	run := Run{ScheduledTime: scheduledTime, StartTime: scheduledTime.Add(5 * time.Minute), Trigger: ManualRun, Attempt: 2}
+	newCronJob := func(inject bool) *CronJob {
+		cronJob := &CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nightly", UID: "uid"}}
+		cronJob.Spec.InjectRunMetadata = &inject
+		cronJob.Spec.JobTemplate.Spec.Template.Spec = corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "init"}},
+			Containers: []corev1.Container{
+				{Name: "main", Env: []corev1.EnvVar{{Name: AttemptEnv, Value: "custom"}, {Name: "FOO", Value: "bar"}}},
+				{Name: "sidecar"},
+			},
+		}
+		return cronJob
+	}
+	construct := func(cronJob *CronJob, run Run) *corev1.PodTemplateSpec {
+		t.Helper()
+		job, err := ConstructJobForCronJobRun(cronJob, run, scheme)
+		if err != nil {
+			t.Fatalf("ConstructJobForCronJobRun() error = %v", err)
+		}
+		return &job.Spec.Template
+	}
+	// env returns the variables of every container, failing on any that is set twice.
+	env := func(template *corev1.PodTemplateSpec) map[string]map[string]string {
+		t.Helper()
+		env := make(map[string]map[string]string)
+		containers := append(append([]corev1.Container{}, template.Spec.InitContainers...), template.Spec.Containers...)
+		for _, container := range containers {
+			env[container.Name] = make(map[string]string)
+			for _, v := range container.Env {
+				if _, ok := env[container.Name][v.Name]; ok {
+					t.Errorf("%s: %s is set twice", container.Name, v.Name)
+				}
+				env[container.Name][v.Name] = v.Value
+			}
+		}
+		return env
+	}
+	runID := "nightly-" + strconv.FormatInt(run.StartTime.Unix(), 10)
+	metadata := map[string]string{
+		CronJobNameAnnotation:   "nightly",
+		ScheduledTimeAnnotation: "2023-01-01T12:00:00Z",
+		RunIDAnnotation:         runID,
+		AttemptAnnotation:       "2",
+		TriggerAnnotation:       "Manual",
+	}
+
+	t.Run("sets the annotations and the environment of every container", func(t *testing.T) {
+		cronJob := newCronJob(true)
+		template := construct(cronJob, run)
+		for annotation, want := range metadata {
+			if got := template.Annotations[annotation]; got != want {
+				t.Errorf("annotation %s = %q, want %q", annotation, got, want)
+			}
+		}
+		for container, got := range env(template) {
+			for annotation, want := range metadata {
+				name := runMetadataEnv[annotation]
+				if container == "main" && name == AttemptEnv {
+					want = "custom"
+				}
+				if got[name] != want {
+					t.Errorf("%s: %s = %q, want %q", container, name, got[name], want)
+				}
+			}
+		}
+		if got := env(template)["main"]["FOO"]; got != "bar" {
+			t.Errorf("main: FOO = %q, want %q", got, "bar")
+		}
+		if got := len(cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[1].Env); got != 0 {
+			t.Errorf("the CronJob's template got %d variables, want it left alone", got)
+		}
+	})
+
+	t.Run("leaves the variables of the template alone", func(t *testing.T) {
+		template := construct(newCronJob(true), run)
+		container := template.Spec.Containers[0]
+		if container.Env[0] != (corev1.EnvVar{Name: AttemptEnv, Value: "custom"}) || container.Env[1] != (corev1.EnvVar{Name: "FOO", Value: "bar"}) {
+			t.Errorf("main: env = %v, want the template's variables first and unchanged", container.Env)
+		}
+	})
+
+	t.Run("sets the matrix entry", func(t *testing.T) {
+		cronJob := newCronJob(true)
+		cronJob.Spec.Matrix = []MatrixEntry{{Name: "eu", Env: []corev1.EnvVar{{Name: "REGION", Value: "eu-west-1"}, {Name: "FOO", Value: "baz"}}}}
+		run := cronJob.RunsForMatrix(run)[0]
+		template := construct(cronJob, run)
+		if got := template.Annotations[RunIDAnnotation]; got != runID+"-eu" {
+			t.Errorf("annotation %s = %q, want %q", RunIDAnnotation, got, runID+"-eu")
+		}
+		for container, got := range env(template) {
+			want := map[string]string{MatrixEntryEnv: "eu", "REGION": "eu-west-1", RunIDEnv: runID + "-eu"}
+			if container == "main" {
+				// the entry's variables take precedence over the template's
+				want["FOO"] = "baz"
+			}
+			for name, want := range want {
+				if got[name] != want {
+					t.Errorf("%s: %s = %q, want %q", container, name, got[name], want)
+				}
+			}
+		}
+	})
+
+	t.Run("is off by default", func(t *testing.T) {
+		cronJob := newCronJob(false)
+		cronJob.Spec.InjectRunMetadata = nil
+		template := construct(cronJob, run)
+		for annotation := range metadata {
+			if _, ok := template.Annotations[annotation]; ok {
+				t.Errorf("annotation %s is set", annotation)
+			}
+		}
+		for container, got := range env(template) {
+			for _, name := range runMetadataEnv {
+				if _, ok := got[name]; ok && !(container == "main" && name == AttemptEnv) {
+					t.Errorf("%s: %s is set", container, name)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	kbatch "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CronJobLabel is set on the objects created from a ResourceTemplate to the name of their
+// CronJob. Unlike Jobs, those can't be indexed by owner, so they are selected by label.
+const CronJobLabel = "batch.tutorial.kubebuilder.io/cronjob"
+
+// The conditions that decide the outcome of an object when the ResourceTemplate doesn't
+// say otherwise.
+var (
+	defaultSuccessConditions = []ResourceCondition{{Type: "Succeeded", Status: metav1.ConditionTrue}}
+	defaultFailureConditions = []ResourceCondition{{Type: "Succeeded", Status: metav1.ConditionFalse}}
+)
+
+// ConstructForCronJobRun builds the object for a run of the CronJob: a Job, or an object
+// of the kind of the ResourceTemplate if it has one.
+func ConstructForCronJobRun(cronJob *CronJob, run Run, scheme *runtime.Scheme) (client.Object, error) {
+	if cronJob.HasAction() {
+		return nil, fmt.Errorf("CronJob %s runs an action, not objects", cronJob.Name)
+	}
+	if cronJob.Spec.ResourceTemplate != nil {
+		return ConstructResourceForCronJobRun(cronJob, run, scheme)
+	}
+	return ConstructJobForCronJobRun(cronJob, run, scheme)
+}
+
+// GroupVersionKind returns the kind of the objects created from the template.
+func (t *ResourceTemplate) GroupVersionKind() (schema.GroupVersionKind, error) {
+	obj, err := t.unstructured()
+	if err != nil {
+		return schema.GroupVersionKind{}, err
+	}
+	gvk := obj.GroupVersionKind()
+	if gvk.Kind == "" || gvk.Version == "" {
+		return schema.GroupVersionKind{}, fmt.Errorf("resource template needs an apiVersion and a kind")
+	}
+	return gvk, nil
+}
+
+func (t *ResourceTemplate) unstructured() (*unstructured.Unstructured, error) {
+	raw := t.Object.Raw
+	if raw == nil && t.Object.Object != nil {
+		var err error
+		if raw, err = json.Marshal(t.Object.Object); err != nil {
+			return nil, err
+		}
+	}
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(raw); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+/*
+ConstructResourceForCronJobRun builds the object of the ResourceTemplate for a run, the way
+ConstructJobForCronJobRun builds Jobs: named after the start time of the run, annotated
+with its scheduled time, and controlled by the CronJob. There is no pod template to set
+the environment of, so matrix entries don't apply, and injected run metadata ends up in
+the annotations of the object itself.
+*/
+func ConstructResourceForCronJobRun(cronJob *CronJob, run Run, scheme *runtime.Scheme) (*unstructured.Unstructured, error) {
+	obj, err := cronJob.Spec.ResourceTemplate.unstructured()
+	if err != nil {
+		return nil, err
+	}
+	startTime := run.StartTime
+	if startTime.IsZero() {
+		startTime = run.ScheduledTime
+	}
+	obj.SetName(fmt.Sprintf("%s-%d", cronJob.Name, startTime.Unix()))
+	obj.SetNamespace(cronJob.Namespace)
+	obj.SetGenerateName("")
+	obj.SetResourceVersion("")
+	obj.SetUID("")
+
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels[CronJobLabel] = cronJob.Name
+	obj.SetLabels(labels)
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[ScheduledTimeAnnotation] = run.ScheduledTime.Format(time.RFC3339)
+	annotations[ScheduleGenerationAnnotation] = strconv.FormatInt(cronJob.Status.ScheduleGeneration, 10)
+	annotations[TriggerAnnotation] = string(run.Trigger)
+	annotations[AttemptAnnotation] = strconv.Itoa(run.Attempt)
+	if cronJob.Spec.InjectRunMetadata != nil && *cronJob.Spec.InjectRunMetadata {
+		annotations[CronJobNameAnnotation] = cronJob.Name
+		annotations[RunIDAnnotation] = obj.GetName()
+	}
+	obj.SetAnnotations(annotations)
+
+	if err := ctrl.SetControllerReference(cronJob, obj, scheme); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// IsFinished tells whether the object is finished according to the completion conditions
+// of the template. The outcome is reported like that of a Job, as JobComplete or JobFailed.
+func (t *ResourceTemplate) IsFinished(obj *unstructured.Unstructured) (bool, kbatch.JobConditionType) {
+	successConditions, failureConditions := t.SuccessConditions, t.FailureConditions
+	if len(successConditions) == 0 {
+		successConditions = defaultSuccessConditions
+	}
+	if len(failureConditions) == 0 {
+		failureConditions = defaultFailureConditions
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	matches := func(want []ResourceCondition) bool {
+		for _, c := range conditions {
+			c, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for _, w := range want {
+				if c["type"] == w.Type && c["status"] == string(w.Status) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	// a failure wins over a success, should an object manage to be both
+	if matches(failureConditions) {
+		return true, kbatch.JobFailed
+	}
+	if matches(successConditions) {
+		return true, kbatch.JobComplete
+	}
+	return false, ""
+}
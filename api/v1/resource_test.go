@@ -0,0 +1,63 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"testing"
+
+	kbatch "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestResourceTemplateIsFinished(t *testing.T) {
+	withConditions := func(conditions ...map[string]interface{}) *unstructured.Unstructured {
+		var list []interface{}
+		for _, c := range conditions {
+			list = append(list, c)
+		}
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"status": map[string]interface{}{"conditions": list},
+		}}
+	}
+	condition := func(conditionType, status string) map[string]interface{} {
+		return map[string]interface{}{"type": conditionType, "status": status}
+	}
+	ready := &ResourceTemplate{
+		SuccessConditions: []ResourceCondition{{Type: "Ready", Status: metav1.ConditionTrue}},
+		FailureConditions: []ResourceCondition{{Type: "Stalled", Status: metav1.ConditionTrue}},
+	}
+
+	for name, tc := range map[string]struct {
+		template *ResourceTemplate
+		obj      *unstructured.Unstructured
+		want     kbatch.JobConditionType
+	}{
+		"no status":              {&ResourceTemplate{}, &unstructured.Unstructured{Object: map[string]interface{}{}}, ""},
+		"default running":        {&ResourceTemplate{}, withConditions(condition("Succeeded", "Unknown")), ""},
+		"default succeeded":      {&ResourceTemplate{}, withConditions(condition("Succeeded", "True")), kbatch.JobComplete},
+		"default failed":         {&ResourceTemplate{}, withConditions(condition("Succeeded", "False")), kbatch.JobFailed},
+		"custom succeeded":       {ready, withConditions(condition("Ready", "True"), condition("Stalled", "False")), kbatch.JobComplete},
+		"custom failed":          {ready, withConditions(condition("Ready", "True"), condition("Stalled", "True")), kbatch.JobFailed},
+		"custom ignores default": {ready, withConditions(condition("Succeeded", "True")), ""},
+	} {
+		finished, got := tc.template.IsFinished(tc.obj)
+		if got != tc.want || finished != (tc.want != "") {
+			t.Errorf("%s: IsFinished() = %v, %q, want %q", name, finished, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ScheduledJobSpec defines the desired state of ScheduledJob
+type ScheduledJobSpec struct {
+	// The time to run the Job at
+	RunAt metav1.Time `json:"runAt"`
+
+	//+kubebuilder:validation:Minimum=0
+
+	// Optional deadline in seconds for starting the job if it misses its
+	// time for any reason. The ScheduledJob is Missed then.
+	// +optional
+	StartingDeadlineSeconds *int64 `json:"startingDeadlineSeconds,omitempty"`
+
+	// Specifies the job that will be created at RunAt
+	JobTemplate batchv1.JobTemplateSpec `json:"jobTemplate"`
+
+	// Specifies what happens to the ScheduledJob once its Job finished or it was missed.
+	// Valid values are:
+	// - "Retain" (default): the ScheduledJob and its Job are kept;
+	// - "Delete": the ScheduledJob is deleted, together with its Job, after TTLSecondsAfterFinished
+	// +optional
+	RetentionPolicy RetentionPolicy `json:"retentionPolicy,omitempty"`
+
+	//+kubebuilder:validation:Minimum=0
+
+	// How long to keep a finished ScheduledJob around before deleting it, when the
+	// RetentionPolicy is "Delete". Defaults to 0.
+	// +optional
+	TTLSecondsAfterFinished *int32 `json:"ttlSecondsAfterFinished,omitempty"`
+}
+
+// RetentionPolicy describes what happens to a ScheduledJob once it is finished.
+// Only one of the following retention policies may be specified.
+// If none of the following policies is specified, the default one
+// is RetainScheduledJob.
+// +kubebuilder:validation:Enum=Retain;Delete
+type RetentionPolicy string
+
+const (
+	// RetainScheduledJob keeps the ScheduledJob and its Job.
+	RetainScheduledJob RetentionPolicy = "Retain"
+
+	// DeleteScheduledJob deletes the ScheduledJob and its Job.
+	DeleteScheduledJob RetentionPolicy = "Delete"
+)
+
+// ScheduledJobPhase is where a ScheduledJob is in its life.
+type ScheduledJobPhase string
+
+const (
+	// ScheduledJobPending is waiting for RunAt.
+	ScheduledJobPending ScheduledJobPhase = "Pending"
+
+	// ScheduledJobRunning has a Job that is still going.
+	ScheduledJobRunning ScheduledJobPhase = "Running"
+
+	// ScheduledJobSucceeded has a Job that succeeded.
+	ScheduledJobSucceeded ScheduledJobPhase = "Succeeded"
+
+	// ScheduledJobFailed has a Job that failed, or one that could not be created.
+	ScheduledJobFailed ScheduledJobPhase = "Failed"
+
+	// ScheduledJobMissed didn't start its Job before the starting deadline.
+	ScheduledJobMissed ScheduledJobPhase = "Missed"
+
+	// ScheduledJobLost had a Job that is gone without being seen to finish, e.g. because
+	// it was deleted. It isn't run again.
+	ScheduledJobLost ScheduledJobPhase = "Lost"
+)
+
+// IsFinished tells whether the ScheduledJob won't do anything anymore.
+func (p ScheduledJobPhase) IsFinished() bool {
+	return p == ScheduledJobSucceeded || p == ScheduledJobFailed || p == ScheduledJobMissed || p == ScheduledJobLost
+}
+
+// ScheduledJobStatus defines the observed state of ScheduledJob
+type ScheduledJobStatus struct {
+	// Where the ScheduledJob is in its life
+	// +optional
+	Phase ScheduledJobPhase `json:"phase,omitempty"`
+
+	// A pointer to the Job, once it was created
+	// +optional
+	Job *corev1.ObjectReference `json:"job,omitempty"`
+
+	// Information when the Job finished, or the ScheduledJob was missed
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Run At",type=date,JSONPath=`.spec.runAt`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ScheduledJob is the Schema for the scheduledjobs API. It runs a Job exactly once,
+// at an absolute point in time.
+type ScheduledJob struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ScheduledJobSpec   `json:"spec,omitempty"`
+	Status ScheduledJobStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ScheduledJobList contains a list of ScheduledJob
+type ScheduledJobList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ScheduledJob `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ScheduledJob{}, &ScheduledJobList{})
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	validationutils "k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var scheduledjoblog = logf.Log.WithName("scheduledjob-resource")
+
+const validateScheduledJobPath = "/validate-batch-tutorial-kubebuilder-io-v1-scheduledjob"
+
+// SetupWebhookWithManager registers the ScheduledJob webhook with the manager. Like for
+// CronJobs, ScheduledJobs in namespaces other than watchNamespaces are admitted without
+// checks.
+func (r *ScheduledJob) SetupWebhookWithManager(mgr ctrl.Manager, watchNamespaces ...string) error {
+	decoder, err := admission.NewDecoder(mgr.GetScheme())
+	if err != nil {
+		return err
+	}
+
+	// There is nothing to default, so the validating webhook is all there is, and it needs
+	// the API server as much as the one of CronJobs does.
+	mgr.GetWebhookServer().Register(validateScheduledJobPath, &webhook.Admission{
+		Handler: &scheduledJobValidator{workloadValidator{
+			Client:          mgr.GetClient(),
+			Scheme:          mgr.GetScheme(),
+			WatchNamespaces: watchNamespaces,
+			decoder:         decoder,
+		}},
+	})
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-batch-tutorial-kubebuilder-io-v1-scheduledjob,mutating=false,failurePolicy=fail,sideEffects=None,groups=batch.tutorial.kubebuilder.io,resources=scheduledjobs,verbs=create;update,versions=v1,name=vscheduledjob.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &ScheduledJob{}
+
+/*
+scheduledJobValidator serves the validating webhook of ScheduledJobs. A ScheduledJob has the
+controller run a Job just like a CronJob does, so on top of the static checks below it goes
+through the same CronJobPolicies, SubjectAccessReviews and dry-run of the Job.
+*/
+// +kubebuilder:object:generate=false
+type scheduledJobValidator struct {
+	workloadValidator
+}
+
+var _ admission.Handler = &scheduledJobValidator{}
+
+// Handle implements admission.Handler
+func (v *scheduledJobValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if !v.watches(req.Namespace) {
+		return admission.Allowed("namespace is not watched by this instance")
+	}
+
+	scheduledJob := &ScheduledJob{}
+	switch req.Operation {
+	case admissionv1.Create:
+		if err := v.decoder.Decode(req, scheduledJob); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if err := scheduledJob.ValidateCreate(); err != nil {
+			return denied(err)
+		}
+	case admissionv1.Update:
+		oldScheduledJob := &ScheduledJob{}
+		if err := v.decoder.DecodeRaw(req.Object, scheduledJob); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if err := v.decoder.DecodeRaw(req.OldObject, oldScheduledJob); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if err := scheduledJob.ValidateUpdate(oldScheduledJob); err != nil {
+			return denied(err)
+		}
+		if apiequality.Semantic.DeepEqual(oldScheduledJob.Spec, scheduledJob.Spec) {
+			return admission.Allowed("")
+		}
+	default:
+		return admission.Allowed("")
+	}
+
+	if scheduledJob.Namespace == "" {
+		scheduledJob.Namespace = req.Namespace
+	}
+	allErrs, err := v.validateAgainstPolicies(ctx, scheduledJob.Namespace, scheduledJob.validateCronJobPolicy)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if len(allErrs) == 0 {
+		if allErrs, err = v.authorize(ctx, req.UserInfo, scheduledJob); err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+	}
+	if len(allErrs) == 0 {
+		allErrs = v.dryRunJob(ctx, scheduledJob)
+	}
+	if len(allErrs) > 0 {
+		return denied(apierrors.NewInvalid(
+			schema.GroupKind{Group: "batch.tutorial.kubebuilder.io", Kind: "ScheduledJob"},
+			scheduledJob.Name, allErrs))
+	}
+	return admission.Allowed("")
+}
+
+// authorize checks that user may create the Job of the ScheduledJob.
+func (v *scheduledJobValidator) authorize(ctx context.Context, user authenticationv1.UserInfo, scheduledJob *ScheduledJob) (field.ErrorList, error) {
+	return v.reviewAccess(ctx, user, []accessCheck{
+		jobAccess(field.NewPath("spec").Child("jobTemplate"), scheduledJob.Namespace),
+	})
+}
+
+// dryRunJob dry-runs the Job the ScheduledJob is going to create, and a Pod of it.
+func (v *scheduledJobValidator) dryRunJob(ctx context.Context, scheduledJob *ScheduledJob) field.ErrorList {
+	fldPath := field.NewPath("spec").Child("jobTemplate")
+	job, err := ConstructJobForScheduledJob(scheduledJob, v.Scheme)
+	if err != nil {
+		return field.ErrorList{field.InternalError(fldPath, err)}
+	}
+	return v.dryRunJobAndPod(ctx, job, fldPath)
+}
+
+// validateCronJobPolicy reports the images of the job template the policy doesn't allow. The
+// other limits of a CronJobPolicy are about schedules and histories, which a ScheduledJob
+// doesn't have.
+func (r *ScheduledJob) validateCronJobPolicy(policy *CronJobPolicy) field.ErrorList {
+	return validateImagePolicy(policy, &r.Spec.JobTemplate.Spec.Template.Spec,
+		field.NewPath("spec").Child("jobTemplate", "spec", "template", "spec"))
+}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *ScheduledJob) ValidateCreate() error {
+	scheduledjoblog.Info("validate create", "name", r.Name)
+
+	return r.validateScheduledJob()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *ScheduledJob) ValidateUpdate(old runtime.Object) error {
+	scheduledjoblog.Info("validate update", "name", r.Name)
+
+	return r.validateScheduledJob()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *ScheduledJob) ValidateDelete() error {
+	return nil
+}
+
+func (r *ScheduledJob) validateScheduledJob() error {
+	var allErrs field.ErrorList
+	if err := r.validateScheduledJobName(); err != nil {
+		allErrs = append(allErrs, err)
+	}
+	if len(r.Spec.JobTemplate.Spec.Template.Spec.Containers) == 0 {
+		allErrs = append(allErrs, field.Required(
+			field.NewPath("spec").Child("jobTemplate", "spec", "template", "spec", "containers"), "the Job needs a container"))
+	}
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(
+		schema.GroupKind{Group: "batch.tutorial.kubebuilder.io", Kind: "ScheduledJob"},
+		r.Name, allErrs)
+}
+
+// The Job is named after the ScheduledJob with the same 11-character suffix as the Jobs of
+// a CronJob, so the name has the same limit.
+func (r *ScheduledJob) validateScheduledJobName() *field.Error {
+	if max := validationutils.DNS1035LabelMaxLength - 11; len(r.Name) > max {
+		return field.Invalid(field.NewPath("metadata").Child("name"), r.Name, fmt.Sprintf("must be no more than %d characters", max))
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestValidateScheduledJob(t *testing.T) {
+	template := kbatch.JobTemplateSpec{Spec: kbatch.JobSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+		Containers: []corev1.Container{{Name: "migrate", Image: "migrate"}},
+	}}}}
+	for name, tc := range map[string]struct {
+		scheduledJob *ScheduledJob
+		valid        bool
+	}{
+		"valid": {
+			scheduledJob: &ScheduledJob{ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("a", 52)}, Spec: ScheduledJobSpec{JobTemplate: template}},
+			valid:        true,
+		},
+		"name too long": {
+			scheduledJob: &ScheduledJob{ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("a", 53)}, Spec: ScheduledJobSpec{JobTemplate: template}},
+		},
+		"no containers": {
+			scheduledJob: &ScheduledJob{ObjectMeta: metav1.ObjectMeta{Name: "migrate"}},
+		},
+	} {
+		if err := tc.scheduledJob.ValidateCreate(); (err == nil) != tc.valid {
+			t.Errorf("%s: ValidateCreate() = %v, want valid %t", name, err, tc.valid)
+		}
+		if err := tc.scheduledJob.ValidateUpdate(tc.scheduledJob.DeepCopy()); (err == nil) != tc.valid {
+			t.Errorf("%s: ValidateUpdate() = %v, want valid %t", name, err, tc.valid)
+		}
+	}
+}
+
+func TestScheduledJobValidator(t *testing.T) {
+	newScheduledJob := func(namespace, image string) *ScheduledJob {
+		return &ScheduledJob{
+			TypeMeta:   metav1.TypeMeta{APIVersion: GroupVersion.String(), Kind: "ScheduledJob"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "migrate"},
+			Spec: ScheduledJobSpec{
+				RunAt: metav1.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+				JobTemplate: kbatch.JobTemplateSpec{Spec: kbatch.JobSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers:    []corev1.Container{{Name: "migrate", Image: image}},
+				}}}},
+			},
+		}
+	}
+	policy := &CronJobPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "registries"},
+		Spec:       CronJobPolicySpec{AllowedRegistries: []string{"registry.example.com"}},
+	}
+	allowAll := func(*authorizationv1.ResourceAttributes) bool { return true }
+	forbiddenOn := func(t *testing.T, resp admission.Response, fields ...string) {
+		t.Helper()
+		if resp.Allowed {
+			t.Fatalf("admitted, want %v forbidden", fields)
+		}
+		causes := resp.Result.Details.Causes
+		if len(causes) != len(fields) {
+			t.Fatalf("denied with %v, want %v forbidden", causes, fields)
+		}
+		for i, cause := range causes {
+			if cause.Field != fields[i] || cause.Type != metav1.CauseType(field.ErrorTypeForbidden) {
+				t.Errorf("cause %d = %+v, want %s forbidden", i, cause, fields[i])
+			}
+		}
+	}
+
+	t.Run("compliant", func(t *testing.T) {
+		base, c := newTestWorkloadValidator(t, allowAll, policy)
+		v := &scheduledJobValidator{base}
+		scheduledJob := newScheduledJob("team-a", "registry.example.com/migrate")
+		if resp := v.Handle(context.Background(), admissionRequest(t, admissionv1.Create, "team-a", scheduledJob, nil)); !resp.Allowed {
+			t.Fatalf("denied: %v", resp.Result)
+		}
+		if len(c.reviews) != 1 || c.reviews[0].ResourceAttributes.Resource != "jobs" || c.reviews[0].User != "jane" {
+			t.Errorf("reviewed %+v, want jane creating Jobs", c.reviews)
+		}
+	})
+
+	t.Run("policy", func(t *testing.T) {
+		base, _ := newTestWorkloadValidator(t, allowAll, policy)
+		v := &scheduledJobValidator{base}
+		resp := v.Handle(context.Background(), admissionRequest(t, admissionv1.Create, "team-a", newScheduledJob("team-a", "busybox"), nil))
+		forbiddenOn(t, resp, "spec.jobTemplate.spec.template.spec.containers[0].image")
+	})
+
+	t.Run("access", func(t *testing.T) {
+		base, _ := newTestWorkloadValidator(t, func(*authorizationv1.ResourceAttributes) bool { return false })
+		v := &scheduledJobValidator{base}
+		resp := v.Handle(context.Background(), admissionRequest(t, admissionv1.Create, "team-a", newScheduledJob("team-a", "busybox"), nil))
+		forbiddenOn(t, resp, "spec.jobTemplate")
+	})
+
+	t.Run("dry-run", func(t *testing.T) {
+		base, c := newTestWorkloadValidator(t, allowAll)
+		c.dryRunErr = apierrors.NewForbidden(schema.GroupResource{Group: "batch", Resource: "jobs"}, "migrate", errors.New("exceeded quota"))
+		v := &scheduledJobValidator{base}
+		resp := v.Handle(context.Background(), admissionRequest(t, admissionv1.Create, "team-a", newScheduledJob("team-a", "busybox"), nil))
+		forbiddenOn(t, resp, "spec.jobTemplate")
+	})
+
+	t.Run("unchanged spec", func(t *testing.T) {
+		base, c := newTestWorkloadValidator(t, allowAll, policy)
+		v := &scheduledJobValidator{base}
+		old := newScheduledJob("team-a", "busybox")
+		scheduledJob := old.DeepCopy()
+		scheduledJob.Labels = map[string]string{"team": "a"}
+		if resp := v.Handle(context.Background(), admissionRequest(t, admissionv1.Update, "team-a", scheduledJob, old)); !resp.Allowed {
+			t.Errorf("a label change was denied: %v", resp.Result)
+		}
+		if len(c.reviews) != 0 {
+			t.Errorf("%d SubjectAccessReviews for a label change, want none", len(c.reviews))
+		}
+	})
+
+	t.Run("other namespace", func(t *testing.T) {
+		base, c := newTestWorkloadValidator(t, allowAll, policy)
+		base.WatchNamespaces = []string{"team-b"}
+		v := &scheduledJobValidator{base}
+		if resp := v.Handle(context.Background(), admissionRequest(t, admissionv1.Create, "team-a", newScheduledJob("team-a", "busybox"), nil)); !resp.Allowed {
+			t.Errorf("denied in a namespace of another instance: %v", resp.Result)
+		}
+		if len(c.reviews) != 0 {
+			t.Errorf("%d SubjectAccessReviews in a namespace of another instance, want none", len(c.reviews))
+		}
+	})
+}
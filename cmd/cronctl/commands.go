@@ -0,0 +1,490 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	batchv1 "tutorial.kubebuilder.io/project/api/v1"
+)
+
+var listCommand = &command{
+	usage: "list",
+	run: func(ctx context.Context, c *cli, args []string) error {
+		var cronJobs batchv1.CronJobList
+		if err := c.client.List(ctx, &cronJobs, client.InNamespace(c.namespace)); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		w := tabwriter.NewWriter(c.out, 0, 8, 3, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSCHEDULE\tSUSPEND\tACTIVE\tLAST RUN\tNEXT RUN")
+		for i := range cronJobs.Items {
+			cronJob := &cronJobs.Items[i]
+			next := "<none>"
+			if runs, _ := cronJob.NextRuns(now, 1); len(runs) > 0 && !isSuspended(cronJob) {
+				next = "in " + duration.HumanDuration(runs[0].Sub(now))
+			}
+			fmt.Fprintf(w, "%s\t%s\t%t\t%d\t%s\t%s\n", cronJob.Name, cronJob.Spec.Schedule,
+				cronJob.IsSuspended(now), len(cronJob.Status.Active), since(cronJob.Status.LastScheduleTime, now), next)
+		}
+		return w.Flush()
+	},
+}
+
+var describeCommand = &command{
+	usage: "describe NAME",
+	run: func(ctx context.Context, c *cli, args []string) error {
+		cronJob, err := c.getCronJob(ctx, args)
+		if err != nil {
+			return err
+		}
+		jobs, err := c.jobsOf(ctx, cronJob)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		w := tabwriter.NewWriter(c.out, 0, 8, 2, ' ', 0)
+		fmt.Fprintf(w, "Name:\t%s\n", cronJob.Name)
+		fmt.Fprintf(w, "Namespace:\t%s\n", cronJob.Namespace)
+		fmt.Fprintf(w, "Schedule:\t%s\n", cronJob.Spec.Schedule)
+		if description, err := batchv1.DescribeSchedule(cronJob.Spec.Schedule); err == nil {
+			fmt.Fprintf(w, "Description:\t%s\n", description)
+		}
+		fmt.Fprintf(w, "Concurrency Policy:\t%s\n", cronJob.Spec.ConcurrencyPolicy)
+		fmt.Fprintf(w, "Suspend:\t%t\n", cronJob.IsSuspended(now))
+		if cronJob.Spec.SuspendUntil != nil {
+			fmt.Fprintf(w, "Suspended Until:\t%s\n", cronJob.Spec.SuspendUntil.Format(time.RFC3339))
+		}
+		if cronJob.Spec.SuspendReason != "" {
+			fmt.Fprintf(w, "Suspend Reason:\t%s\n", cronJob.Spec.SuspendReason)
+		}
+		fmt.Fprintf(w, "Last Schedule Time:\t%s\n", since(cronJob.Status.LastScheduleTime, now))
+		if cronJob.Spec.SuccessDeadline != nil {
+			fmt.Fprintf(w, "Last Successful Time:\t%s\n", since(cronJob.Status.LastSuccessfulTime, now))
+		}
+		runs := fmt.Sprintf("%d succeeded, %d failed", cronJob.Status.SucceededRuns, cronJob.Status.FailedRuns)
+		if cronJob.Spec.MaxRuns != nil {
+			runs += fmt.Sprintf(" (at most %d %s)", *cronJob.Spec.MaxRuns, cronJob.Spec.RunCountPolicy)
+		}
+		fmt.Fprintf(w, "Runs:\t%s\n", runs)
+		if stats := cronJob.Status.DurationStatistics; stats != nil {
+			fmt.Fprintf(w, "Durations:\tp50 %s, p95 %s, max %s (last %d runs)\n", stats.P50.Duration, stats.P95.Duration,
+				stats.Max.Duration, stats.Runs)
+		}
+		fmt.Fprintln(w, "Upcoming Runs:")
+		for _, run := range cronJob.Status.UpcomingRuns {
+			fmt.Fprintf(w, "  %s\n", run.Format(time.RFC3339))
+		}
+		fmt.Fprintln(w, "Conditions:")
+		for _, cond := range cronJob.Status.Conditions {
+			fmt.Fprintf(w, "  %s=%s\t%s\t%s\n", cond.Type, cond.Status, cond.Reason, cond.Message)
+		}
+		if len(cronJob.Status.Matrix) > 0 {
+			fmt.Fprintln(w, "Matrix:")
+			for _, entry := range cronJob.Status.Matrix {
+				fmt.Fprintf(w, "  %s\t%d active\tlast scheduled %s\t%s\n", entry.Name, len(entry.Active),
+					since(entry.LastScheduleTime, now), entry.LastResult)
+			}
+		}
+		fmt.Fprintln(w, "Active Jobs:")
+		for _, ref := range cronJob.Status.Active {
+			fmt.Fprintf(w, "  %s\n", ref.Name)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		// actions leave no Jobs, only their own history
+		if cronJob.HasAction() {
+			fmt.Fprintln(c.out, "History:")
+			w = tabwriter.NewWriter(c.out, 0, 8, 3, ' ', 0)
+			fmt.Fprintln(w, "  SCHEDULED\tCOMPLETED\tRESULT\tDETAILS")
+			for _, record := range cronJob.Status.ActionHistory {
+				completed := "<none>"
+				if record.CompletionTime != nil {
+					completed = record.CompletionTime.Format(time.RFC3339)
+				}
+				fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", record.ScheduledTime.Format(time.RFC3339),
+					completed, record.Result, actionDetails(record))
+			}
+			return w.Flush()
+		}
+
+		fmt.Fprintln(c.out, "History:")
+		w = tabwriter.NewWriter(c.out, 0, 8, 3, ' ', 0)
+		fmt.Fprintln(w, "  JOB\tSCHEDULED\tTRIGGER\tSTATUS\tREASON\tMESSAGE")
+		for _, job := range jobs {
+			status, reason, message := jobStatus(cronJob, job)
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\t%s\n", job.GetName(), job.GetAnnotations()[batchv1.ScheduledTimeAnnotation],
+				job.GetAnnotations()[batchv1.TriggerAnnotation], status, reason, message)
+		}
+		return w.Flush()
+	},
+}
+
+var triggerSlot, triggerEntry string
+
+var triggerCommand = &command{
+	usage: "trigger [--slot TIME] [--entry ENTRY] NAME",
+	flags: func(fs *flag.FlagSet) {
+		fs.StringVar(&triggerSlot, "slot", "",
+			"Run again for this scheduled time, in RFC 3339, e.g. to retry a failed run. Runs for the current time if empty.")
+		fs.StringVar(&triggerEntry, "entry", "", "Only run this entry of the matrix. Runs all of them if empty.")
+	},
+	run: func(ctx context.Context, c *cli, args []string) error {
+		cronJob, err := c.getCronJob(ctx, args)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		run := batchv1.Run{ScheduledTime: now, StartTime: now, Trigger: batchv1.ManualRun, Attempt: 1}
+		if triggerSlot != "" {
+			if run.ScheduledTime, err = time.Parse(time.RFC3339, triggerSlot); err != nil {
+				return fmt.Errorf("invalid --slot: %w", err)
+			}
+		}
+		runs, err := matrixRuns(cronJob, run, triggerEntry)
+		if err != nil {
+			return err
+		}
+		jobs, err := c.jobsOf(ctx, cronJob)
+		if err != nil {
+			return err
+		}
+
+		for _, run := range runs {
+			// every earlier Job for the same slot and entry was an attempt
+			for _, job := range jobs {
+				if scheduledAt, err := time.Parse(time.RFC3339, job.GetAnnotations()[batchv1.ScheduledTimeAnnotation]); err == nil &&
+					scheduledAt.Equal(run.ScheduledTime) && job.GetLabels()[batchv1.MatrixEntryLabel] == entryName(run) {
+					run.Attempt++
+				}
+			}
+			job, err := batchv1.ConstructForCronJobRun(cronJob, run, scheme)
+			if err != nil {
+				return err
+			}
+			if err := c.client.Create(ctx, job); err != nil {
+				return err
+			}
+			fmt.Fprintf(c.out, "%s created\n", objectName(job))
+		}
+		return nil
+	},
+}
+
+var suspendUntil, suspendReason string
+
+var suspendCommand = &command{
+	usage: "suspend [--until TIME|DURATION] [--reason REASON] NAME",
+	flags: func(fs *flag.FlagSet) {
+		fs.StringVar(&suspendUntil, "until", "",
+			"Resume automatically at this time, in RFC 3339, or after this duration, e.g. 2h. Suspends indefinitely if empty.")
+		fs.StringVar(&suspendReason, "reason", "", "Why the CronJob is suspended.")
+	},
+	run: func(ctx context.Context, c *cli, args []string) error {
+		cronJob, err := c.getCronJob(ctx, args)
+		if err != nil {
+			return err
+		}
+		patch := client.MergeFrom(cronJob.DeepCopy())
+		if suspendUntil == "" {
+			suspend := true
+			cronJob.Spec.Suspend = &suspend
+		} else {
+			until, err := parseUntil(suspendUntil, time.Now())
+			if err != nil {
+				return fmt.Errorf("invalid --until: %w", err)
+			}
+			cronJob.Spec.SuspendUntil = &metav1.Time{Time: until}
+		}
+		cronJob.Spec.SuspendReason = suspendReason
+		if err := c.client.Patch(ctx, cronJob, patch); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.out, "cronjob.batch.tutorial.kubebuilder.io/%s suspended\n", cronJob.Name)
+		return nil
+	},
+}
+
+var resumeCommand = &command{
+	usage: "resume NAME",
+	run: func(ctx context.Context, c *cli, args []string) error {
+		cronJob, err := c.getCronJob(ctx, args)
+		if err != nil {
+			return err
+		}
+		patch := client.MergeFrom(cronJob.DeepCopy())
+		suspend := false
+		cronJob.Spec.Suspend = &suspend
+		cronJob.Spec.SuspendUntil = nil
+		cronJob.Spec.SuspendReason = ""
+		if err := c.client.Patch(ctx, cronJob, patch); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.out, "cronjob.batch.tutorial.kubebuilder.io/%s resumed\n", cronJob.Name)
+		return nil
+	},
+}
+
+var nextCount int
+
+var nextCommand = &command{
+	usage: "next [--count N] NAME",
+	flags: func(fs *flag.FlagSet) {
+		fs.IntVar(&nextCount, "count", 5, "The number of runs to print.")
+	},
+	run: func(ctx context.Context, c *cli, args []string) error {
+		cronJob, err := c.getCronJob(ctx, args)
+		if err != nil {
+			return err
+		}
+		if isSuspended(cronJob) {
+			fmt.Fprintf(c.out, "# %s is suspended, these runs happen only once it is resumed\n", cronJob.Name)
+		} else if cronJob.IsSuspended(time.Now()) {
+			fmt.Fprintf(c.out, "# %s is suspended until %s\n", cronJob.Name, cronJob.Spec.SuspendUntil.Format(time.RFC3339))
+		}
+		runs, err := cronJob.NextRuns(time.Now(), nextCount)
+		if err != nil {
+			return err
+		}
+		for _, run := range runs {
+			fmt.Fprintln(c.out, run.Format(time.RFC3339))
+		}
+		return nil
+	},
+}
+
+var renderJobAt string
+
+var renderJobEntry string
+
+var renderJobCommand = &command{
+	usage: "render-job [--at TIME] [--entry ENTRY] NAME",
+	flags: func(fs *flag.FlagSet) {
+		fs.StringVar(&renderJobAt, "at", "", "The scheduled time of the run to render, in RFC 3339. Defaults to the next run.")
+		fs.StringVar(&renderJobEntry, "entry", "", "Only render the Job of this entry of the matrix. Renders all of them if empty.")
+	},
+	run: func(ctx context.Context, c *cli, args []string) error {
+		cronJob, err := c.getCronJob(ctx, args)
+		if err != nil {
+			return err
+		}
+
+		var scheduledTime time.Time
+		if renderJobAt != "" {
+			if scheduledTime, err = time.Parse(time.RFC3339, renderJobAt); err != nil {
+				return fmt.Errorf("invalid --at: %w", err)
+			}
+		} else {
+			runs, err := cronJob.NextRuns(time.Now(), 1)
+			if err != nil {
+				return err
+			}
+			if len(runs) == 0 {
+				return fmt.Errorf("CronJob %s has no upcoming run, pass --at", cronJob.Name)
+			}
+			scheduledTime = runs[0]
+		}
+
+		runs, err := matrixRuns(cronJob, batchv1.Run{ScheduledTime: scheduledTime, Trigger: batchv1.ScheduledRun, Attempt: 1}, renderJobEntry)
+		if err != nil {
+			return err
+		}
+		for i, run := range runs {
+			job, err := batchv1.ConstructForCronJobRun(cronJob, run, scheme)
+			if err != nil {
+				return err
+			}
+			if _, ok := job.(*kbatch.Job); ok {
+				job.GetObjectKind().SetGroupVersionKind(kbatch.SchemeGroupVersion.WithKind("Job"))
+			}
+			out, err := yaml.Marshal(job)
+			if err != nil {
+				return err
+			}
+			if i > 0 {
+				fmt.Fprintln(c.out, "---")
+			}
+			if _, err := c.out.Write(out); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+func (c *cli) getCronJob(ctx context.Context, args []string) (*batchv1.CronJob, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("expected exactly one CronJob name, got %d arguments", len(args))
+	}
+	var cronJob batchv1.CronJob
+	if err := c.client.Get(ctx, client.ObjectKey{Namespace: c.namespace, Name: args[0]}, &cronJob); err != nil {
+		return nil, err
+	}
+	return &cronJob, nil
+}
+
+// jobsOf returns the objects controlled by the CronJob, oldest run first: its Jobs, or the
+// objects of its resource template, which are found by their label like the controller does.
+func (c *cli) jobsOf(ctx context.Context, cronJob *batchv1.CronJob) ([]client.Object, error) {
+	var candidates []client.Object
+	if cronJob.Spec.ResourceTemplate == nil {
+		var jobs kbatch.JobList
+		if err := c.client.List(ctx, &jobs, client.InNamespace(cronJob.Namespace)); err != nil {
+			return nil, err
+		}
+		for i := range jobs.Items {
+			candidates = append(candidates, &jobs.Items[i])
+		}
+	} else {
+		gvk, err := cronJob.Spec.ResourceTemplate.GroupVersionKind()
+		if err != nil {
+			return nil, err
+		}
+		var list unstructured.UnstructuredList
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := c.client.List(ctx, &list, client.InNamespace(cronJob.Namespace), client.MatchingLabels{batchv1.CronJobLabel: cronJob.Name}); err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			candidates = append(candidates, &list.Items[i])
+		}
+	}
+
+	var owned []client.Object
+	for _, obj := range candidates {
+		if metav1.IsControlledBy(obj, cronJob) {
+			owned = append(owned, obj)
+		}
+	}
+	sort.Slice(owned, func(i, j int) bool {
+		return owned[i].GetAnnotations()[batchv1.ScheduledTimeAnnotation] < owned[j].GetAnnotations()[batchv1.ScheduledTimeAnnotation]
+	})
+	return owned, nil
+}
+
+// objectName names the object the way kubectl does, e.g. job.batch/hello-1672531200.
+func objectName(obj client.Object) string {
+	if _, ok := obj.(*kbatch.Job); ok {
+		return "job.batch/" + obj.GetName()
+	}
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	kind := strings.ToLower(gvk.Kind)
+	if gvk.Group != "" {
+		kind += "." + gvk.Group
+	}
+	return kind + "/" + obj.GetName()
+}
+
+// actionDetails sums up what a run of an action did, or why it failed.
+func actionDetails(record batchv1.ActionRecord) string {
+	switch {
+	case record.Result == batchv1.ActionFailed && record.HTTP != nil:
+		return fmt.Sprintf("%s (%d attempts)", record.Message, record.HTTP.Attempts)
+	case record.Result == batchv1.ActionFailed:
+		return record.Message
+	case record.HTTP != nil && record.HTTP.NextAttemptTime != nil:
+		return fmt.Sprintf("%s, retrying at %s (%d attempts)", record.Message,
+			record.HTTP.NextAttemptTime.Format(time.RFC3339), record.HTTP.Attempts)
+	case record.Scale != nil:
+		return fmt.Sprintf("%s/%s %d -> %d replicas", record.Scale.Kind, record.Scale.Name,
+			record.Scale.PreviousReplicas, record.Scale.Replicas)
+	case record.HTTP != nil:
+		return fmt.Sprintf("%d after %s", record.HTTP.StatusCode, record.HTTP.Latency.Duration)
+	}
+	return ""
+}
+
+// matrixRuns returns the run for each entry of the CronJob's matrix, or only for the
+// given one.
+func matrixRuns(cronJob *batchv1.CronJob, run batchv1.Run, entry string) ([]batchv1.Run, error) {
+	runs := cronJob.RunsForMatrix(run)
+	if entry == "" {
+		return runs, nil
+	}
+	for _, run := range runs {
+		if entryName(run) == entry {
+			return []batchv1.Run{run}, nil
+		}
+	}
+	return nil, fmt.Errorf("CronJob %s has no matrix entry %q", cronJob.Name, entry)
+}
+
+func entryName(run batchv1.Run) string {
+	if run.MatrixEntry == nil {
+		return ""
+	}
+	return run.MatrixEntry.Name
+}
+
+// parseUntil accepts either a point in time or a duration from now.
+func parseUntil(until string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(until); err == nil {
+		return now.Add(d), nil
+	}
+	return time.Parse(time.RFC3339, until)
+}
+
+// isSuspended tells whether the CronJob is suspended indefinitely.
+func isSuspended(cronJob *batchv1.CronJob) bool {
+	return cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend
+}
+
+// jobStatus sums up a Job the way `kubectl get jobs` would. Objects of a resource template
+// are finished according to its conditions, and reported like Jobs.
+func jobStatus(cronJob *batchv1.CronJob, obj client.Object) (status, reason, message string) {
+	job, ok := obj.(*kbatch.Job)
+	if !ok {
+		if u, ok := obj.(*unstructured.Unstructured); ok && cronJob.Spec.ResourceTemplate != nil {
+			if finished, finishedType := cronJob.Spec.ResourceTemplate.IsFinished(u); finished {
+				return string(finishedType), "", ""
+			}
+		}
+		return "Running", "", ""
+	}
+	for _, c := range job.Status.Conditions {
+		if (c.Type == kbatch.JobComplete || c.Type == kbatch.JobFailed) && c.Status == corev1.ConditionTrue {
+			return string(c.Type), c.Reason, c.Message
+		}
+	}
+	return "Running", "", ""
+}
+
+func since(t *metav1.Time, now time.Time) string {
+	if t == nil {
+		return "<none>"
+	}
+	return duration.HumanDuration(now.Sub(t.Time)) + " ago"
+}
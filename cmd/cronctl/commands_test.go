@@ -0,0 +1,221 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	batchv1 "tutorial.kubebuilder.io/project/api/v1"
+)
+
+var slot = time.Date(2023, 1, 1, 2, 0, 0, 0, time.UTC)
+
+func testCronJob() *batchv1.CronJob {
+	return &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "report", Namespace: "default", UID: "report-uid"},
+		Spec: batchv1.CronJobSpec{
+			Schedule: "0 2 * * *",
+			JobTemplate: kbatch.JobTemplateSpec{Spec: kbatch.JobSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "report", Image: "report"}},
+			}}}},
+			Matrix: []batchv1.MatrixEntry{{Name: "eu"}, {Name: "us"}},
+		},
+	}
+}
+
+// testJob is a Job the controller made for the CronJob at slot, for the matrix entry.
+func testJob(t *testing.T, cronJob *batchv1.CronJob, entry string, attempt int) *kbatch.Job {
+	run := batchv1.Run{ScheduledTime: slot, StartTime: slot.Add(time.Duration(attempt) * time.Minute), Trigger: batchv1.ScheduledRun, Attempt: attempt}
+	for _, r := range cronJob.RunsForMatrix(run) {
+		if entryName(r) == entry {
+			job, err := batchv1.ConstructJobForCronJobRun(cronJob, r, scheme)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return job
+		}
+	}
+	t.Fatalf("no matrix entry %q", entry)
+	return nil
+}
+
+func newTestCLI(objs ...client.Object) (*cli, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	return &cli{client: c, namespace: "default", out: out}, out
+}
+
+func TestTrigger(t *testing.T) {
+	cronJob := testCronJob()
+	c, out := newTestCLI(cronJob, testJob(t, cronJob, "eu", 1), testJob(t, cronJob, "eu", 2), testJob(t, cronJob, "us", 1))
+	ctx := context.Background()
+
+	triggerSlot, triggerEntry = slot.Format(time.RFC3339), "eu"
+	defer func() { triggerSlot, triggerEntry = "", "" }()
+	if err := triggerCommand.run(ctx, c, []string{"report"}); err != nil {
+		t.Fatal(err)
+	}
+	if created := strings.Count(out.String(), "created"); created != 1 {
+		t.Fatalf("trigger --entry created %d Jobs, want 1:\n%s", created, out)
+	}
+
+	attempts := map[string][]string{}
+	var jobs kbatch.JobList
+	if err := c.client.List(ctx, &jobs); err != nil {
+		t.Fatal(err)
+	}
+	for _, job := range jobs.Items {
+		if job.Annotations[batchv1.TriggerAnnotation] == string(batchv1.ManualRun) {
+			attempts[job.Labels[batchv1.MatrixEntryLabel]] = append(attempts[job.Labels[batchv1.MatrixEntryLabel]], job.Annotations[batchv1.AttemptAnnotation])
+			if job.Annotations[batchv1.ScheduledTimeAnnotation] != triggerSlot {
+				t.Errorf("Job %s is for %s, want %s", job.Name, job.Annotations[batchv1.ScheduledTimeAnnotation], triggerSlot)
+			}
+		}
+	}
+	if got := attempts["eu"]; len(got) != 1 || got[0] != "3" || len(attempts["us"]) != 0 {
+		t.Errorf("manual runs have the attempts %v, want attempt 3 of eu only", attempts)
+	}
+
+	triggerEntry = "asia"
+	if err := triggerCommand.run(ctx, c, []string{"report"}); err == nil {
+		t.Error("trigger --entry accepted an unknown matrix entry")
+	}
+}
+
+func TestTriggerAllEntries(t *testing.T) {
+	cronJob := testCronJob()
+	c, out := newTestCLI(cronJob)
+	if err := triggerCommand.run(context.Background(), c, []string{"report"}); err != nil {
+		t.Fatal(err)
+	}
+	if created := strings.Count(out.String(), "created"); created != 2 {
+		t.Errorf("trigger created %d Jobs, want one per matrix entry:\n%s", created, out)
+	}
+}
+
+func TestTriggerResourceTemplate(t *testing.T) {
+	cronJob := testCronJob()
+	cronJob.Spec.Matrix = nil
+	cronJob.Spec.ResourceTemplate = &batchv1.ResourceTemplate{Object: runtime.RawExtension{
+		Raw: []byte(`{"apiVersion": "tekton.dev/v1", "kind": "PipelineRun", "spec": {"pipelineRef": {"name": "report"}}}`),
+	}}
+	previous, err := batchv1.ConstructResourceForCronJobRun(cronJob,
+		batchv1.Run{ScheduledTime: slot, Trigger: batchv1.ScheduledRun, Attempt: 1}, scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, out := newTestCLI(cronJob, previous)
+	ctx := context.Background()
+
+	triggerSlot = slot.Format(time.RFC3339)
+	defer func() { triggerSlot = "" }()
+	if err := triggerCommand.run(ctx, c, []string{"report"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "pipelinerun.tekton.dev/") {
+		t.Fatalf("trigger didn't create a PipelineRun:\n%s", out)
+	}
+
+	runs, err := c.jobsOf(ctx, cronJob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("jobsOf() found %d PipelineRuns, want 2", len(runs))
+	}
+	for _, run := range runs {
+		if run.GetAnnotations()[batchv1.TriggerAnnotation] == string(batchv1.ManualRun) && run.GetAnnotations()[batchv1.AttemptAnnotation] != "2" {
+			t.Errorf("manual run is attempt %s, want 2", run.GetAnnotations()[batchv1.AttemptAnnotation])
+		}
+	}
+
+	out.Reset()
+	if err := describeCommand.run(ctx, c, []string{"report"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), previous.GetName()) {
+		t.Errorf("describe doesn't list the PipelineRun %s:\n%s", previous.GetName(), out)
+	}
+}
+
+func TestParseUntil(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	for until, want := range map[string]time.Time{
+		"2h":                   now.Add(2 * time.Hour),
+		"90m":                  now.Add(90 * time.Minute),
+		"2023-01-02T08:00:00Z": time.Date(2023, 1, 2, 8, 0, 0, 0, time.UTC),
+	} {
+		got, err := parseUntil(until, now)
+		if err != nil {
+			t.Errorf("parseUntil(%q) failed: %v", until, err)
+			continue
+		}
+		if !got.Equal(want) {
+			t.Errorf("parseUntil(%q) = %s, want %s", until, got, want)
+		}
+	}
+	for _, until := range []string{"", "tomorrow", "2023-01-02"} {
+		if _, err := parseUntil(until, now); err == nil {
+			t.Errorf("parseUntil(%q) succeeded", until)
+		}
+	}
+}
+
+func TestRenderJob(t *testing.T) {
+	c, out := newTestCLI(testCronJob())
+	renderJobAt, renderJobEntry = slot.Format(time.RFC3339), "us"
+	defer func() { renderJobAt, renderJobEntry = "", "" }()
+	if err := renderJobCommand.run(context.Background(), c, []string{"report"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var job kbatch.Job
+	if err := yaml.Unmarshal(out.Bytes(), &job); err != nil {
+		t.Fatalf("render-job printed something other than a Job: %v\n%s", err, out)
+	}
+	if job.Kind != "Job" || job.Name != "report-1672538400-us" || job.Labels[batchv1.MatrixEntryLabel] != "us" ||
+		job.Annotations[batchv1.ScheduledTimeAnnotation] != renderJobAt {
+		t.Errorf("render-job printed\n%s", out)
+	}
+
+	// nothing is created
+	var jobs kbatch.JobList
+	if err := c.client.List(context.Background(), &jobs); err != nil || len(jobs.Items) != 0 {
+		t.Errorf("render-job left %d Jobs behind (%v)", len(jobs.Items), err)
+	}
+
+	out.Reset()
+	renderJobEntry = ""
+	if err := renderJobCommand.run(context.Background(), c, []string{"report"}); err != nil {
+		t.Fatal(err)
+	}
+	if docs := strings.Count(out.String(), "\n---\n"); docs != 1 {
+		t.Errorf("render-job without --entry printed %d Jobs, want 2:\n%s", docs+1, out)
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// cronctl is a small command-line tool for operators of CronJobs. It talks to the
+// cluster of the current kubeconfig context, like kubectl does.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	batchv1 "tutorial.kubebuilder.io/project/api/v1"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(batchv1.AddToScheme(scheme))
+}
+
+// command is a cronctl subcommand. run gets the arguments left over after flag parsing.
+type command struct {
+	usage string
+	flags func(fs *flag.FlagSet)
+	run   func(ctx context.Context, c *cli, args []string) error
+}
+
+var commands = map[string]*command{
+	"list":       listCommand,
+	"describe":   describeCommand,
+	"trigger":    triggerCommand,
+	"suspend":    suspendCommand,
+	"resume":     resumeCommand,
+	"next":       nextCommand,
+	"render-job": renderJobCommand,
+}
+
+var commandOrder = []string{"list", "describe", "trigger", "suspend", "resume", "next", "render-job"}
+
+// cli carries what every command needs: a client, the namespace to work in and
+// where to write to.
+type cli struct {
+	client    client.Client
+	namespace string
+	out       io.Writer
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "cronctl: unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("cronctl "+os.Args[1], flag.ExitOnError)
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	fs.StringVar(&loadingRules.ExplicitPath, "kubeconfig", "", "Path to the kubeconfig file to use.")
+	var namespace string
+	fs.StringVar(&namespace, "namespace", "", "The namespace of the CronJobs. Defaults to the namespace of the current context.")
+	fs.StringVar(&namespace, "n", "", "Shorthand for --namespace.")
+	if cmd.flags != nil {
+		cmd.flags(fs)
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: cronctl %s\n\nFlags:\n", cmd.usage)
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(os.Args[2:])
+
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{})
+	if namespace == "" {
+		var err error
+		if namespace, _, err = clientConfig.Namespace(); err != nil {
+			fail(err)
+		}
+	}
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		fail(err)
+	}
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		fail(err)
+	}
+
+	if err := cmd.run(context.Background(), &cli{client: c, namespace: namespace, out: os.Stdout}, fs.Args()); err != nil {
+		fail(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: cronctl <command> [flags] [arguments]\n\nCommands:")
+	for _, name := range commandOrder {
+		fmt.Fprintf(os.Stderr, "  %s\n", commands[name].usage)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun 'cronctl <command> -h' for the flags of a command.")
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "cronctl: %v\n", err)
+	os.Exit(1)
+}
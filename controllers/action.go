@@ -0,0 +1,295 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	batchv1 "tutorial.kubebuilder.io/project/api/v1"
+)
+
+//+kubebuilder:rbac:groups=apps,resources=deployments/scale;statefulsets/scale,verbs=get;update
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get
+
+// httpRetryBackoff is how long to wait before the first retry of an HTTP action; it doubles
+// with every retry after that.
+var httpRetryBackoff = time.Second
+
+// httpClient sends the requests of HTTP actions. It doesn't follow redirects: the headers
+// may hold credentials meant for the URL of the action only, and that URL is the one the
+// webhook checked against the policies.
+var httpClient = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+/*
+runAction carries out the action of the CronJob for the run at scheduledTime, and records
+the outcome in the action history. The history is what tells us the run happened, so it
+has to make it into the status. An action that can't possibly work, e.g. because its
+target doesn't exist, is recorded as failed like a failed Job would be; anything else is
+returned, so that we retry. A retry picks up the record of the run the first attempt
+left behind, if it got that far. A run that isn't over yet, i.e. an HTTP action waiting
+to retry, returns when to come back for it.
+*/
+func (r *CronJobReconciler) runAction(ctx context.Context, cronJob *batchv1.CronJob, scheduledTime time.Time) (retryAt time.Time, err error) {
+	log := log.FromContext(ctx)
+
+	record := batchv1.ActionRecord{
+		ScheduledTime: metav1.Time{Time: scheduledTime},
+		Result:        batchv1.ActionRunning,
+	}
+	if running := cronJob.Status.RunningAction(); running != nil && running.ScheduledTime.Equal(&record.ScheduledTime) {
+		record = *running.DeepCopy()
+	}
+	switch {
+	case cronJob.Spec.ScaleAction != nil:
+		err = r.scale(ctx, cronJob, &record)
+	case cronJob.Spec.HTTPAction != nil:
+		retryAt, err = r.callHTTP(ctx, cronJob, &record)
+	}
+	switch {
+	case apierrors.IsNotFound(err):
+		record.Result = batchv1.ActionFailed
+		record.Message = err.Error()
+	case err != nil:
+		return time.Time{}, err
+	}
+
+	// not over yet, but the status has to know when it goes on
+	if !retryAt.IsZero() {
+		cronJob.Status.RecordAction(record)
+		if err := r.Status().Update(ctx, cronJob); err != nil {
+			return time.Time{}, err
+		}
+		log.V(1).Info("waiting to retry action for CronJob run", "attempts", record.HTTP.Attempts, "next attempt", retryAt)
+		return retryAt, nil
+	}
+
+	if record.Result == batchv1.ActionRunning {
+		record.Result = batchv1.ActionSucceeded
+	}
+	record.CompletionTime = &metav1.Time{Time: r.Now()}
+
+	cronJob.Status.RecordAction(record)
+	if err := r.Status().Update(ctx, cronJob); err != nil {
+		return time.Time{}, err
+	}
+
+	switch {
+	case record.Result == batchv1.ActionFailed:
+		r.Recorder.Eventf(cronJob, corev1.EventTypeWarning, "FailedAction", "Action for the run at %s failed: %s",
+			scheduledTime.Format(time.RFC3339), record.Message)
+	case record.Scale != nil:
+		r.Recorder.Eventf(cronJob, corev1.EventTypeNormal, "Scaled", "Scaled %s %s from %d to %d replicas",
+			record.Scale.Kind, record.Scale.Name, record.Scale.PreviousReplicas, record.Scale.Replicas)
+	case record.HTTP != nil:
+		r.Recorder.Eventf(cronJob, corev1.EventTypeNormal, "Called", "%s %s returned %d after %s",
+			cronJob.Spec.HTTPAction.Method, cronJob.Spec.HTTPAction.URL, record.HTTP.StatusCode, record.HTTP.Latency.Duration)
+	}
+	log.V(1).Info("ran action for CronJob run", "result", record.Result)
+	return time.Time{}, nil
+}
+
+/*
+scale sets the replicas of the target of the action through its scale subresource, which is
+all the access to the workload we need. The replicas the target had go on record in the
+status before they are changed: a retry of the run, e.g. after the status update at its
+end failed, finds them changed already, and would take the new replicas for the old ones.
+*/
+func (r *CronJobReconciler) scale(ctx context.Context, cronJob *batchv1.CronJob, record *batchv1.ActionRecord) error {
+	action := cronJob.Spec.ScaleAction
+	var target client.Object
+	switch action.TargetRef.Kind {
+	case "Deployment":
+		target = &appsv1.Deployment{}
+	case "StatefulSet":
+		target = &appsv1.StatefulSet{}
+	default:
+		return fmt.Errorf("unsupported kind %q", action.TargetRef.Kind)
+	}
+	target.SetNamespace(cronJob.Namespace)
+	target.SetName(action.TargetRef.Name)
+
+	scale := &autoscalingv1.Scale{}
+	if err := r.SubResource("scale").Get(ctx, target, scale); err != nil {
+		return err
+	}
+	if record.Scale == nil {
+		record.Scale = &batchv1.ScaleRecord{
+			Kind:             action.TargetRef.Kind,
+			Name:             action.TargetRef.Name,
+			PreviousReplicas: scale.Spec.Replicas,
+			Replicas:         action.Replicas,
+		}
+		cronJob.Status.RecordAction(*record)
+		if err := r.Status().Update(ctx, cronJob); err != nil {
+			return err
+		}
+	}
+	if scale.Spec.Replicas == action.Replicas {
+		return nil
+	}
+	scale.Spec.Replicas = action.Replicas
+	return r.SubResource("scale").Update(ctx, target, client.WithSubResourceBody(scale))
+}
+
+// getSecret reads a Secret of the namespace. We don't want every Secret of the cluster in
+// the cache, so it comes straight from the API server.
+func (r *CronJobReconciler) getSecret(ctx context.Context, namespace string, ref *corev1.LocalObjectReference) (*corev1.Secret, error) {
+	reader := r.APIReader
+	if reader == nil {
+		reader = r.Client
+	}
+	var secret corev1.Secret
+	if err := reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ref.Name}, &secret); err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
+
+// secretHeaders returns HTTP headers from a Secret holding one per key, none without a Secret.
+func (r *CronJobReconciler) secretHeaders(ctx context.Context, namespace string, ref *corev1.LocalObjectReference) (http.Header, error) {
+	headers := make(http.Header)
+	if ref == nil {
+		return headers, nil
+	}
+	secret, err := r.getSecret(ctx, namespace, ref)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range secret.Data {
+		headers.Set(name, string(value))
+	}
+	return headers, nil
+}
+
+/*
+callHTTP makes the next attempt at the request of the action, one per reconcile: rather
+than sleeping through the backoff, it returns when the retry after a failed attempt is
+due. The outcome goes into the record: it stays running while there are retries left,
+and failed once there aren't.
+
+Every attempt is on record in the status before its request goes out. An attempt we
+find on record without an outcome was cut short, e.g. by a failed status update or by
+the shard changing hands, and the request may well have been sent, so rather than risk
+sending it twice, the run is given up on.
+*/
+func (r *CronJobReconciler) callHTTP(ctx context.Context, cronJob *batchv1.CronJob, record *batchv1.ActionRecord) (time.Time, error) {
+	action := cronJob.Spec.HTTPAction
+	if record.HTTP == nil {
+		record.HTTP = &batchv1.HTTPRecord{}
+	}
+	switch next := record.HTTP.NextAttemptTime; {
+	case record.HTTP.Attempts > 0 && next == nil:
+		record.Result = batchv1.ActionFailed
+		record.Message = fmt.Sprintf("attempt %d was interrupted, and isn't repeated in case its request went out", record.HTTP.Attempts)
+		return time.Time{}, nil
+	case next != nil && r.Now().Before(next.Time):
+		return next.Time, nil
+	}
+
+	headers, err := r.secretHeaders(ctx, cronJob.Namespace, action.HeadersSecretRef)
+	if err != nil {
+		return time.Time{}, err
+	}
+	record.HTTP.Attempts++
+	record.HTTP.NextAttemptTime = nil
+	cronJob.Status.RecordAction(*record)
+	if err := r.Status().Update(ctx, cronJob); err != nil {
+		return time.Time{}, err
+	}
+
+	timeout := batchv1.DefaultHTTPTimeout
+	if action.Timeout != nil {
+		timeout = action.Timeout.Duration
+	}
+	err = sendHTTP(ctx, action, headers, timeout, record.HTTP)
+	if err == nil {
+		record.Message = ""
+		return time.Time{}, nil
+	}
+	record.Message = err.Error()
+	if action.Retries == nil || record.HTTP.Attempts > *action.Retries {
+		record.Result = batchv1.ActionFailed
+		return time.Time{}, nil
+	}
+	retryAt := r.Now().Add(httpRetryBackoff << (record.HTTP.Attempts - 1))
+	record.HTTP.NextAttemptTime = &metav1.Time{Time: retryAt}
+	return retryAt, nil
+}
+
+// sendHTTP makes a single attempt at the request of the action, recording the response.
+func sendHTTP(ctx context.Context, action *batchv1.HTTPAction, headers http.Header, timeout time.Duration, record *batchv1.HTTPRecord) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	method := action.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequestWithContext(ctx, method, action.URL, strings.NewReader(action.Body))
+	if err != nil {
+		return err
+	}
+	req.Header = headers.Clone()
+
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+	record.Latency = metav1.Duration{Duration: time.Since(start)}
+	record.StatusCode = 0
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// drain (some of) the body, so that the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	record.StatusCode = int32(resp.StatusCode)
+	if !expectedStatusCode(action.ExpectedStatusCodes, resp.StatusCode) {
+		return fmt.Errorf("unexpected response status %q", resp.Status)
+	}
+	return nil
+}
+
+// expectedStatusCode tells whether the code is one of the expected ones, or a 2xx code if
+// none are.
+func expectedStatusCode(expected []int32, code int) bool {
+	if len(expected) == 0 {
+		return code >= 200 && code < 300
+	}
+	for _, e := range expected {
+		if int(e) == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,362 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	batchv1 "tutorial.kubebuilder.io/project/api/v1"
+)
+
+func TestSendHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		switch req.URL.Path {
+		case "/ok":
+			if req.Method != http.MethodPut || req.Header.Get("Authorization") != "Bearer token" || string(body) != `{"dry":false}` {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+		case "/slow":
+			time.Sleep(50 * time.Millisecond)
+		case "/not-modified":
+			w.WriteHeader(http.StatusNotModified)
+		case "/redirect":
+			http.Redirect(w, req, "/ok", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	for name, tc := range map[string]struct {
+		action   batchv1.HTTPAction
+		wantErr  bool
+		wantCode int32
+	}{
+		"method, headers and body": {
+			action:   batchv1.HTTPAction{URL: server.URL + "/ok", Method: http.MethodPut, Body: `{"dry":false}`},
+			wantCode: http.StatusAccepted,
+		},
+		"expected status code": {
+			action:   batchv1.HTTPAction{URL: server.URL + "/not-modified", ExpectedStatusCodes: []int32{http.StatusNotModified}},
+			wantCode: http.StatusNotModified,
+		},
+		"unexpected status code": {
+			action:  batchv1.HTTPAction{URL: server.URL + "/not-modified"},
+			wantErr: true, wantCode: http.StatusNotModified,
+		},
+		"redirects aren't followed": {
+			action:  batchv1.HTTPAction{URL: server.URL + "/redirect", Method: http.MethodPut, Body: `{"dry":false}`},
+			wantErr: true, wantCode: http.StatusFound,
+		},
+		"timeout": {
+			action:  batchv1.HTTPAction{URL: server.URL + "/slow"},
+			wantErr: true,
+		},
+	} {
+		headers := http.Header{"Authorization": []string{"Bearer token"}}
+		record := &batchv1.HTTPRecord{}
+		err := sendHTTP(context.Background(), &tc.action, headers, 10*time.Millisecond, record)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: sendHTTP() error = %v, want error %t", name, err, tc.wantErr)
+		}
+		if record.StatusCode != tc.wantCode {
+			t.Errorf("%s: sendHTTP() = status %d, want status %d", name, record.StatusCode, tc.wantCode)
+		}
+		if record.Latency.Duration <= 0 {
+			t.Errorf("%s: sendHTTP() recorded no latency", name)
+		}
+	}
+}
+
+func TestHTTPAction(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	at := func(hour, minute int) time.Time { return time.Date(2023, 1, 1, hour, minute, 0, 0, time.UTC) }
+	key := types.NamespacedName{Namespace: "default", Name: "refresh"}
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	newCronJob := func(retries int32) *batchv1.CronJob {
+		return &batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name, CreationTimestamp: metav1.Time{Time: at(11, 0)}},
+			Spec: batchv1.CronJobSpec{
+				Schedule:   "0 * * * *",
+				HTTPAction: &batchv1.HTTPAction{URL: server.URL, Retries: &retries},
+			},
+		}
+	}
+	reconcile := func(r *CronJobReconciler, now time.Time) ctrl.Result {
+		t.Helper()
+		r.Clock = &fakeClock{now: now}
+		result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		if err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+		return result
+	}
+	history := func(c client.Client) []batchv1.ActionRecord {
+		t.Helper()
+		var got batchv1.CronJob
+		if err := c.Get(ctx, key, &got); err != nil {
+			t.Fatal(err)
+		}
+		return got.Status.ActionHistory
+	}
+
+	t.Run("retries in later reconciles", func(t *testing.T) {
+		requests = 0
+		r, c := newTestCronJobReconciler(t, scheme, at(12, 0), newCronJob(3))
+
+		// the first two attempts fail, and are retried after 1s, then 2s
+		start := at(12, 0)
+		if result := reconcile(r, start); result.RequeueAfter != time.Second || requests != 1 {
+			t.Fatalf("first attempt: RequeueAfter = %v after %d requests, want 1s after 1", result.RequeueAfter, requests)
+		}
+		if records := history(c); len(records) != 1 || records[0].Result != batchv1.ActionRunning || records[0].HTTP.Attempts != 1 {
+			t.Fatalf("history = %+v, want the run waiting to retry after 1 attempt", records)
+		}
+		if result := reconcile(r, start.Add(500*time.Millisecond)); result.RequeueAfter != 500*time.Millisecond || requests != 1 {
+			t.Fatalf("too early: RequeueAfter = %v after %d requests, want 500ms after 1", result.RequeueAfter, requests)
+		}
+		if result := reconcile(r, start.Add(time.Second)); result.RequeueAfter != 2*time.Second || requests != 2 {
+			t.Fatalf("second attempt: RequeueAfter = %v after %d requests, want 2s after 2", result.RequeueAfter, requests)
+		}
+		reconcile(r, start.Add(3*time.Second))
+		if requests != 3 {
+			t.Fatalf("%d requests, want 3", requests)
+		}
+		records := history(c)
+		if len(records) != 1 || records[0].Result != batchv1.ActionSucceeded || records[0].HTTP.Attempts != 3 ||
+			records[0].HTTP.StatusCode != http.StatusNoContent || records[0].HTTP.NextAttemptTime != nil {
+			t.Errorf("history = %+v, want the run succeeded after 3 attempts", records)
+		}
+
+		reconcile(r, start.Add(time.Minute))
+		if requests != 3 {
+			t.Errorf("%d requests after the run was over, want 3", requests)
+		}
+	})
+
+	t.Run("out of retries", func(t *testing.T) {
+		requests = 0
+		r, c := newTestCronJobReconciler(t, scheme, at(12, 0), newCronJob(1))
+		reconcile(r, at(12, 0))
+		reconcile(r, at(12, 0).Add(time.Second))
+		if records := history(c); len(records) != 1 || records[0].Result != batchv1.ActionFailed || records[0].HTTP.Attempts != 2 {
+			t.Errorf("history = %+v, want the run failed after 2 attempts", records)
+		}
+	})
+
+	t.Run("doesn't send twice", func(t *testing.T) {
+		requests = 0
+		r, c := newTestCronJobReconciler(t, scheme, at(12, 0), newCronJob(3))
+		// the update with the outcome of the first attempt fails, e.g. on a conflict
+		r.Client = &actionClient{Client: c, failStatus: func(cronJob *batchv1.CronJob) bool {
+			running := cronJob.Status.RunningAction()
+			return running != nil && running.HTTP != nil && running.HTTP.NextAttemptTime != nil
+		}}
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err == nil {
+			t.Fatal("Reconcile() succeeded, want the failed status update")
+		}
+		if requests != 1 {
+			t.Fatalf("%d requests, want 1", requests)
+		}
+		if records := history(c); len(records) != 1 || records[0].Result != batchv1.ActionRunning || records[0].HTTP.Attempts != 1 {
+			t.Fatalf("history = %+v, want the first attempt on record", records)
+		}
+
+		r.Client = c
+		reconcile(r, at(12, 1))
+		if requests != 1 {
+			t.Errorf("%d requests, want the interrupted attempt not repeated", requests)
+		}
+		if records := history(c); len(records) != 1 || records[0].Result != batchv1.ActionFailed {
+			t.Errorf("history = %+v, want the run failed", records)
+		}
+	})
+}
+
+// actionClient serves the scale subresource of Deployments, which the fake client doesn't,
+// and fails the status updates of CronJobs that failStatus picks.
+type actionClient struct {
+	client.Client
+	failStatus func(*batchv1.CronJob) bool
+}
+
+func (c *actionClient) SubResource(subResource string) client.SubResourceClient {
+	if subResource == "scale" {
+		return deploymentScale{c.Client}
+	}
+	return c.Client.SubResource(subResource)
+}
+
+func (c *actionClient) Status() client.SubResourceWriter {
+	return failingStatusWriter{c.Client.Status(), c.failStatus}
+}
+
+type failingStatusWriter struct {
+	client.SubResourceWriter
+	fail func(*batchv1.CronJob) bool
+}
+
+func (w failingStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+	if cronJob, ok := obj.(*batchv1.CronJob); ok && w.fail != nil && w.fail(cronJob) {
+		return errors.New("status update failed")
+	}
+	return w.SubResourceWriter.Update(ctx, obj, opts...)
+}
+
+type deploymentScale struct {
+	client.Client
+}
+
+func (c deploymentScale) Get(ctx context.Context, obj, subResource client.Object, opts ...client.SubResourceGetOption) error {
+	var deployment appsv1.Deployment
+	if err := c.Client.Get(ctx, client.ObjectKeyFromObject(obj), &deployment); err != nil {
+		return err
+	}
+	subResource.(*autoscalingv1.Scale).Spec.Replicas = *deployment.Spec.Replicas
+	return nil
+}
+
+func (c deploymentScale) Create(ctx context.Context, obj, subResource client.Object, opts ...client.SubResourceCreateOption) error {
+	return errors.New("not supported")
+}
+
+func (c deploymentScale) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+	var options client.SubResourceUpdateOptions
+	options.ApplyOptions(opts)
+	var deployment appsv1.Deployment
+	if err := c.Client.Get(ctx, client.ObjectKeyFromObject(obj), &deployment); err != nil {
+		return err
+	}
+	replicas := options.SubResourceBody.(*autoscalingv1.Scale).Spec.Replicas
+	deployment.Spec.Replicas = &replicas
+	return c.Client.Update(ctx, &deployment)
+}
+
+func (c deploymentScale) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	return errors.New("not supported")
+}
+
+func TestScale(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	at := func(hour, minute int) time.Time { return time.Date(2023, 1, 1, hour, minute, 0, 0, time.UTC) }
+	key := types.NamespacedName{Namespace: "default", Name: "scale-down"}
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name, CreationTimestamp: metav1.Time{Time: at(19, 0)}},
+		Spec: batchv1.CronJobSpec{
+			Schedule: "0 20 * * *",
+			ScaleAction: &batchv1.ScaleAction{
+				TargetRef: batchv1.ScaleTargetReference{Kind: "Deployment", Name: "web"},
+				Replicas:  0,
+			},
+		},
+	}
+	replicas := int32(3)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: "web"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+	}
+	history := func(c client.Client) []batchv1.ActionRecord {
+		t.Helper()
+		var got batchv1.CronJob
+		if err := c.Get(ctx, key, &got); err != nil {
+			t.Fatal(err)
+		}
+		return got.Status.ActionHistory
+	}
+
+	t.Run("keeps the previous replicas across retries", func(t *testing.T) {
+		r, c := newTestCronJobReconciler(t, scheme, at(20, 0), cronJob.DeepCopy(), deployment.DeepCopy())
+		// the update at the end of the run fails once, after the Deployment was scaled
+		failed := false
+		r.Client = &actionClient{Client: c, failStatus: func(cronJob *batchv1.CronJob) bool {
+			running := cronJob.Status.RunningAction()
+			if failed || len(cronJob.Status.ActionHistory) == 0 || running != nil {
+				return false
+			}
+			failed = true
+			return true
+		}}
+
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err == nil {
+			t.Fatal("Reconcile() succeeded, want the failed status update")
+		}
+		if records := history(c); len(records) != 1 || records[0].Result != batchv1.ActionRunning || records[0].Scale.PreviousReplicas != 3 {
+			t.Fatalf("history after the failed update = %+v, want the run with 3 previous replicas", records)
+		}
+		var scaled appsv1.Deployment
+		if err := c.Get(ctx, client.ObjectKeyFromObject(deployment), &scaled); err != nil {
+			t.Fatal(err)
+		}
+		if *scaled.Spec.Replicas != 0 {
+			t.Fatalf("Deployment has %d replicas, want 0", *scaled.Spec.Replicas)
+		}
+
+		r.Clock = &fakeClock{now: at(20, 1)}
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+		records := history(c)
+		if len(records) != 1 {
+			t.Fatalf("history = %+v, want a single run", records)
+		}
+		record := records[0]
+		if record.Result != batchv1.ActionSucceeded || !record.ScheduledTime.Time.Equal(at(20, 0)) || record.CompletionTime == nil {
+			t.Errorf("record = %+v, want the run at 20:00 succeeded", record)
+		}
+		if record.Scale == nil || record.Scale.PreviousReplicas != 3 || record.Scale.Replicas != 0 {
+			t.Errorf("scale record = %+v, want 3 -> 0 replicas", record.Scale)
+		}
+	})
+
+	t.Run("fails without a target", func(t *testing.T) {
+		r, c := newTestCronJobReconciler(t, scheme, at(20, 0), cronJob.DeepCopy())
+		r.Client = &actionClient{Client: c}
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+		if records := history(c); len(records) != 1 || records[0].Result != batchv1.ActionFailed || records[0].Scale != nil {
+			t.Errorf("history = %+v, want the run failed", records)
+		}
+	})
+}
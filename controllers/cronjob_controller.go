@@ -20,17 +20,31 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/robfig/cron"
+	"golang.org/x/time/rate"
 	kbatch "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
 	ref "k8s.io/client-go/tools/reference"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/ratelimiter"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	batchv1 "tutorial.kubebuilder.io/project/api/v1"
 )
@@ -40,6 +54,33 @@ type CronJobReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
 	Clock
+	Recorder record.EventRecorder
+
+	// WatchNamespaces restricts the reconciler to CronJobs and Jobs in these
+	// namespaces. All namespaces are reconciled if empty.
+	WatchNamespaces []string
+
+	// Shards, when set, restricts the reconciler to the CronJobs of the shards
+	// this replica owns.
+	Shards *ShardManager
+
+	// MaxConcurrentReconciles is the number of workers of the controller, 1 if not set.
+	// The workqueue never hands the same CronJob to two workers at once, so reconciles
+	// of a single CronJob stay serialized however many workers there are.
+	MaxConcurrentReconciles int
+	// RateLimiter paces the retries of failed reconciles. Defaults to the
+	// workqueue's default controller rate limiter.
+	RateLimiter ratelimiter.RateLimiter
+
+	// APIReader reads what we don't want to cache, i.e. the Secrets of HTTP actions.
+	// Defaults to the Client.
+	APIReader client.Reader
+
+	// controller is what we add the watches for the kinds of resource templates to, as we
+	// come across them; watched are the kinds we watch already.
+	controller controller.Controller
+	watchedMu  sync.Mutex
+	watched    map[schema.GroupVersionKind]bool
 }
 
 // StartMock: This is to mock the actual time
@@ -53,15 +94,12 @@ type Clock interface {
 
 // EndMock
 
-var (
-	scheduledTimeAnnnotation = "batch.tutorial.kubebuilder.io/scheduled-at"
-)
-
 //+kubebuilder:rbac:groups=batch.tutorial.kubebuilder.io,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=batch.tutorial.kubebuilder.io,resources=cronjobs/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=batch.tutorial.kubebuilder.io,resources=cronjobs/finalizers,verbs=update
 //+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=batch,resources=jobs/status,verbs=get
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -72,8 +110,21 @@ var (
 //
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.14.1/pkg/reconcile
-func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
 	log := log.FromContext(ctx)
+
+	// In sharding mode another replica may own this CronJob, in which case it's none of
+	// our business. It will be requeued when we acquire its shard. Otherwise we hold on to
+	// the shard until we are done.
+	if r.Shards != nil {
+		done, owned := r.Shards.Track(req.NamespacedName)
+		if !owned {
+			log.V(1).Info("cronjob belongs to a shard of another replica, skipping")
+			return ctrl.Result{}, nil
+		}
+		defer done()
+	}
+
 	// ########################################## //
 	// 1: Load the CronJob by name
 	// ########################################## //
@@ -83,40 +134,40 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		// We'll ignore not-found errors, since they can't be fixed by an immediate
 		// requeue (we'll need to wait for a new notification), and we can get them
 		// on deleted requests
+		if apierrors.IsNotFound(err) {
+			overdueRuns.DeletePartialMatch(map[string]string{"namespace": req.Namespace, "cronjob": req.Name})
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
 	// ########################################## //
 	// 2: List all active jobs and update the status
 	// ########################################## //
-	var childJobs kbatch.JobList
-	if err := r.List(ctx, &childJobs, client.InNamespace(req.Namespace), client.MatchingFields{jobOwnerKey: req.Name}); err != nil {
+	// A CronJob with a resource template runs objects of that kind rather than Jobs, which
+	// we need to watch before we hear about them finishing. From here on, "jobs" are
+	// whatever the CronJob runs.
+	if cronJob.Spec.ResourceTemplate != nil {
+		if err := r.watchResource(cronJob.Spec.ResourceTemplate); err != nil {
+			log.Error(err, "unable to watch the kind of the resource template")
+			return ctrl.Result{}, err
+		}
+	}
+	childJobs, err := r.listChildren(ctx, &cronJob)
+	if err != nil {
 		log.Error(err, "Unable to list child Jobs")
 		return ctrl.Result{}, err
 	}
 
 	// find the active list of Jobs
-	var activeJobs []*kbatch.Job
-	var successfulJobs []*kbatch.Job
-	var failedJobs []*kbatch.Job
+	var activeJobs []client.Object
+	var successfulJobs []client.Object
+	var failedJobs []client.Object
 	var mostRecentTime *time.Time // find the last run so we can update the status
-
-	// Job is "finished" if it has a "Complete" or "Failed" condition marked as true. Status
-	// conditions allow us to add extensible status information to our objects that other humans and
-	// controllers can examine to check things like completion and health
-
-	isJobFinished := func(job *kbatch.Job) (bool, kbatch.JobConditionType) {
-		for _, c := range job.Status.Conditions {
-			if (c.Type == kbatch.JobComplete || c.Type == kbatch.JobFailed) && c.Status == corev1.ConditionTrue {
-				return true, c.Type
-			}
-		}
-		return false, ""
-	}
+	var runs []scheduledRun       // what we need to count the runs
 
 	// Helper function to gextract the scheduled time from the annotation that we added during job creation
-	getScheduledTimeForJob := func(job *kbatch.Job) (*time.Time, error) {
-		timeRaw := job.Annotations[scheduledTimeAnnnotation]
+	getScheduledTimeForJob := func(job client.Object) (*time.Time, error) {
+		timeRaw := job.GetAnnotations()[batchv1.ScheduledTimeAnnotation]
 		if len(timeRaw) == 0 {
 			return nil, nil
 		}
@@ -127,26 +178,37 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return &timeParsed, nil
 	}
 
-	for i, job := range childJobs.Items {
-		_, finishedType := isJobFinished(&job)
+	for _, job := range childJobs {
+		finishedType := jobFinishedType(&cronJob, job)
 		switch finishedType {
 		case "": //ongoing
-			activeJobs = append(activeJobs, &childJobs.Items[i])
+			activeJobs = append(activeJobs, job)
 		case kbatch.JobFailed:
-			failedJobs = append(failedJobs, &childJobs.Items[i])
+			failedJobs = append(failedJobs, job)
 		case kbatch.JobComplete:
-			successfulJobs = append(successfulJobs, &childJobs.Items[i])
+			successfulJobs = append(successfulJobs, job)
+		}
+
+		// Runs started by hand don't move the schedule along, nor do they count towards MaxRuns
+		if job.GetAnnotations()[batchv1.TriggerAnnotation] == string(batchv1.ManualRun) {
+			continue
 		}
 
 		// We'll store the launch time in an annotation, so we'll reconstitute that from
 		// the active jobs themselves
-		scheduledTimeForJob, err := getScheduledTimeForJob(&job)
+		scheduledTimeForJob, err := getScheduledTimeForJob(job)
 		if err != nil {
-			log.Error(err, "unable to parse schedule time for child job", "job", &job)
+			log.Error(err, "unable to parse schedule time for child job", "job", job)
 			continue
 		}
 
 		if scheduledTimeForJob != nil {
+			run := scheduledRun{scheduledTime: *scheduledTimeForJob, finishedType: finishedType, duration: jobDuration(job)}
+			if finishedType == kbatch.JobFailed {
+				run.failedJob = job.GetName()
+				run.retriesExhausted = jobRetriesExhausted(job)
+			}
+			runs = append(runs, run)
 			if mostRecentTime == nil {
 				mostRecentTime = scheduledTimeForJob
 			} else if mostRecentTime.Before(*scheduledTimeForJob) {
@@ -155,6 +217,27 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		}
 	}
 
+	// Actions don't leave any Jobs behind, only their history.
+	for _, record := range cronJob.Status.ActionHistory {
+		run := scheduledRun{scheduledTime: record.ScheduledTime.Time, finishedType: kbatch.JobComplete}
+		switch record.Result {
+		case batchv1.ActionFailed:
+			run.finishedType = kbatch.JobFailed
+			// an HTTP action that got to retry gave up in the end
+			run.retriesExhausted = record.HTTP != nil && record.HTTP.Attempts > 1
+		case batchv1.ActionRunning:
+			run.finishedType = ""
+		}
+		if record.HTTP != nil {
+			run.duration = record.HTTP.Latency.Duration
+		}
+		scheduledTime := record.ScheduledTime.Time
+		runs = append(runs, run)
+		if mostRecentTime == nil || mostRecentTime.Before(scheduledTime) {
+			mostRecentTime = &scheduledTime
+		}
+	}
+
 	if mostRecentTime != nil {
 		cronJob.Status.LastScheduleTime = &metav1.Time{Time: *mostRecentTime}
 	} else {
@@ -171,6 +254,120 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		cronJob.Status.Active = append(cronJob.Status.Active, *jobRef)
 	}
 
+	// With a matrix, each entry gets its own share of the status as well. Which ticks an
+	// entry was handled at is all that's carried over, the rest comes from the Jobs.
+	lastHandled := make(map[string]*metav1.Time)
+	for _, entryStatus := range cronJob.Status.Matrix {
+		lastHandled[entryStatus.Name] = entryStatus.LastHandledTime
+	}
+	cronJob.Status.Matrix = nil
+	for _, entry := range cronJob.Spec.Matrix {
+		entryStatus := batchv1.MatrixEntryStatus{Name: entry.Name, LastHandledTime: lastHandled[entry.Name]}
+		var lastFinished time.Time
+		for _, job := range childJobs {
+			if job.GetLabels()[batchv1.MatrixEntryLabel] != entry.Name {
+				continue
+			}
+			scheduledTime, err := getScheduledTimeForJob(job)
+			if err != nil || scheduledTime == nil {
+				continue
+			}
+			if entryStatus.LastScheduleTime == nil || entryStatus.LastScheduleTime.Time.Before(*scheduledTime) {
+				entryStatus.LastScheduleTime = &metav1.Time{Time: *scheduledTime}
+			}
+
+			switch finishedType := jobFinishedType(&cronJob, job); finishedType {
+			case "":
+				jobRef, err := ref.GetReference(r.Scheme, job)
+				if err != nil {
+					log.Error(err, "unable to make reference to active job", "job", job)
+					continue
+				}
+				entryStatus.Active = append(entryStatus.Active, *jobRef)
+			case kbatch.JobComplete, kbatch.JobFailed:
+				if scheduledTime.Before(lastFinished) {
+					continue
+				}
+				lastFinished = *scheduledTime
+				entryStatus.LastResult = "Succeeded"
+				if finishedType == kbatch.JobFailed {
+					entryStatus.LastResult = "Failed"
+				}
+			}
+		}
+		if last := entryStatus.LastScheduleTime; last != nil && (entryStatus.LastHandledTime == nil || entryStatus.LastHandledTime.Before(last)) {
+			entryStatus.LastHandledTime = last.DeepCopy()
+		}
+		cronJob.Status.Matrix = append(cronJob.Status.Matrix, entryStatus)
+	}
+
+	// The Jobs don't stay around forever, so we keep count of the runs in the status instead,
+	// which is what MaxRuns is checked against.
+	countedRuns := countRuns(&cronJob.Status, runs)
+	trackConsecutiveFailures(&cronJob.Status, countedRuns)
+	trackLastSuccess(&cronJob.Status, successfulJobs)
+	oldStats := cronJob.Status.DurationStatistics
+	recordDurations(&cronJob.Status, countedRuns)
+	setDurationExceedsInterval(&cronJob, oldStats, r.Now())
+	if cronJob.Spec.FailurePolicy == nil {
+		meta.RemoveStatusCondition(&cronJob.Status.Conditions, batchv1.CircuitOpenCondition)
+		cronJob.Status.SuspendedByBreaker = false
+	}
+	finishedRuns := cronJob.Status.SucceededRuns
+	if cronJob.Spec.RunCountPolicy == batchv1.CountAllRuns {
+		finishedRuns += cronJob.Status.FailedRuns
+	}
+	if cronJob.Spec.MaxRuns == nil {
+		meta.RemoveStatusCondition(&cronJob.Status.Conditions, batchv1.CompletedCondition)
+	} else if finishedRuns >= int64(*cronJob.Spec.MaxRuns) {
+		meta.SetStatusCondition(&cronJob.Status.Conditions, metav1.Condition{
+			Type:               batchv1.CompletedCondition,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: cronJob.Generation,
+			Reason:             "MaxRunsReached",
+			Message:            fmt.Sprintf("The CronJob made %d of %d runs", finishedRuns, *cronJob.Spec.MaxRuns),
+		})
+	} else {
+		meta.SetStatusCondition(&cronJob.Status.Conditions, metav1.Condition{
+			Type:               batchv1.CompletedCondition,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: cronJob.Generation,
+			Reason:             "RunsRemaining",
+			Message:            fmt.Sprintf("The CronJob made %d of %d runs", finishedRuns, *cronJob.Spec.MaxRuns),
+		})
+	}
+
+	// Keep track of the schedule we are working against.
+	if oldSchedule := cronJob.Status.ObservedSchedule; observeSchedule(&cronJob, r.Now()) && oldSchedule != "" {
+		log.V(1).Info("schedule changed", "old schedule", oldSchedule, "new schedule", cronJob.Spec.Schedule)
+	}
+
+	// Publish the next few runs, so that users can check their schedule without pasting it
+	// into some website. They are worked out in the same time zone as the runs themselves.
+	cronJob.Status.UpcomingRuns = getUpcomingRuns(&cronJob, r.Now())
+	// an unparseable schedule is reported further down, when we actually need it
+	cronJob.Status.ScheduleDescription, _ = batchv1.DescribeSchedule(cronJob.Spec.Schedule)
+
+	if cronJob.Spec.ActiveUntil == nil {
+		meta.RemoveStatusCondition(&cronJob.Status.Conditions, batchv1.ExpiredCondition)
+	} else if cronJob.IsExpired(r.Now()) {
+		meta.SetStatusCondition(&cronJob.Status.Conditions, metav1.Condition{
+			Type:               batchv1.ExpiredCondition,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: cronJob.Generation,
+			Reason:             "ActiveUntilPassed",
+			Message:            fmt.Sprintf("The CronJob stopped running at %s", cronJob.Spec.ActiveUntil.Format(time.RFC3339)),
+		})
+	} else {
+		meta.SetStatusCondition(&cronJob.Status.Conditions, metav1.Condition{
+			Type:               batchv1.ExpiredCondition,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: cronJob.Generation,
+			Reason:             "Active",
+			Message:            fmt.Sprintf("The CronJob runs until %s", cronJob.Spec.ActiveUntil.Format(time.RFC3339)),
+		})
+	}
+
 	// We now log all jobs we observed at a higher log/debug level. We use a fixed message and attach
 	// key-value pairs with the extra informatino. This makes it easier to filter and query log lines
 	log.V(1).Info("job count", "active jobs", len(activeJobs), "successful jobs", len(successfulJobs), "failed jobs", len(failedJobs))
@@ -181,10 +378,36 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	// The Status subresource ignores changes to spec, so it's likely to conflict with other updates
 	// and can have separate permissions
 
+	// Runs that take too long, and CronJobs that go without a success for too long, have to
+	// be noticed when they do, whatever else we're waiting for. That includes a schedule we
+	// gave up on, so we make sure to come back in time on every way out of here.
+	nextCheck := r.checkDurations(ctx, &cronJob, activeJobs)
+	staleMessage, staleNotifications, goesStale := r.checkStale(&cronJob)
+	if !goesStale.IsZero() && (nextCheck.IsZero() || goesStale.Before(nextCheck)) {
+		nextCheck = goesStale
+	}
+	defer func() {
+		if err != nil || nextCheck.IsZero() {
+			return
+		}
+		if after := nextCheck.Sub(r.Now()); after > 0 && (result.RequeueAfter == 0 || after < result.RequeueAfter) {
+			result.RequeueAfter = after
+		}
+	}()
+
+	// Every run is counted exactly once, so the runs we just counted are the ones nobody
+	// has been told about yet. We only tell them once the status says they were counted,
+	// though, or a failed update would have us notify twice.
+	notifications := append(runNotifications(&cronJob, countedRuns, r.Now()), staleNotifications...)
+
 	if err := r.Status().Update(ctx, &cronJob); err != nil {
 		log.Error(err, "unable to update CronJob status")
 		return ctrl.Result{}, err
 	}
+	if staleMessage != "" {
+		r.Recorder.Event(&cronJob, corev1.EventTypeWarning, "Stale", staleMessage)
+	}
+	r.notify(ctx, &cronJob, notifications)
 
 	// ########################################## //
 	// 3: Clean up old jobs according to the history limit
@@ -195,49 +418,207 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 
 	// NB: deleting thse are "best effort" -- if we fail on a particular one,
 	// we won't requeue jsut to finish the deleting
+	// Jobs we haven't counted yet have to stay, or their runs would go unnoticed.
+	isJobCounted := func(job client.Object) bool {
+		if job.GetAnnotations()[batchv1.TriggerAnnotation] == string(batchv1.ManualRun) {
+			return true
+		}
+		scheduledTime, err := getScheduledTimeForJob(job)
+		if err != nil || scheduledTime == nil {
+			return true
+		}
+		return isRunCounted(&cronJob.Status, *scheduledTime)
+	}
 	// 3.1 Clean up failed jobs
+	// (the history limits apply to each matrix entry on its own)
 	if cronJob.Spec.FailedJobsHistoryLimit != nil {
-		sort.Slice(failedJobs, func(i, j int) bool {
-			if failedJobs[i].Status.StartTime == nil {
-				return failedJobs[j].Status.StartTime != nil
-			}
-			return failedJobs[i].Status.StartTime.Before(failedJobs[j].Status.StartTime)
-		})
-		for i, job := range failedJobs {
-			if int32(i) >= int32(len(failedJobs))-*cronJob.Spec.FailedJobsHistoryLimit {
-				break
-			}
-			if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
-				log.Error(err, "unable to delete old failed job", "job", job)
-			} else {
-				log.V(0).Info("deleted old failed job", "job", job)
+		for _, failedJobs := range byMatrixEntry(failedJobs) {
+			sort.Slice(failedJobs, func(i, j int) bool {
+				iStart, jStart := jobStartTime(failedJobs[i]), jobStartTime(failedJobs[j])
+				if iStart == nil {
+					return jStart != nil
+				}
+				return iStart.Before(jStart)
+			})
+			for i, job := range failedJobs {
+				if int32(i) >= int32(len(failedJobs))-*cronJob.Spec.FailedJobsHistoryLimit {
+					break
+				}
+				if !isJobCounted(job) {
+					continue
+				}
+				if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+					log.Error(err, "unable to delete old failed job", "job", job)
+				} else {
+					log.V(0).Info("deleted old failed job", "job", job)
+				}
 			}
 		}
 	}
 
 	// 3.2: Clean up Successful jobs
 	if cronJob.Spec.SuccessfulJobHistoryLimit != nil {
-		sort.Slice(successfulJobs, func(i, j int) bool {
-			if successfulJobs[i].Status.StartTime == nil {
-				return successfulJobs[j].Status.StartTime != nil
-			}
-			return successfulJobs[i].Status.StartTime.Before(successfulJobs[j].Status.StartTime)
-		})
-		for i, job := range successfulJobs {
-			if int32(i) >= int32(len(successfulJobs))-*cronJob.Spec.SuccessfulJobHistoryLimit {
-				break
-			}
-			if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); (err) != nil {
-				log.Error(err, "unable to delete old successful job", "job", job)
-			} else {
-				log.V(0).Info("delete old successful job", "job", job)
+		for _, successfulJobs := range byMatrixEntry(successfulJobs) {
+			sort.Slice(successfulJobs, func(i, j int) bool {
+				iStart, jStart := jobStartTime(successfulJobs[i]), jobStartTime(successfulJobs[j])
+				if iStart == nil {
+					return jStart != nil
+				}
+				return iStart.Before(jStart)
+			})
+			for i, job := range successfulJobs {
+				if int32(i) >= int32(len(successfulJobs))-*cronJob.Spec.SuccessfulJobHistoryLimit {
+					break
+				}
+				if !isJobCounted(job) {
+					continue
+				}
+				if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); (err) != nil {
+					log.Error(err, "unable to delete old successful job", "job", job)
+				} else {
+					log.V(0).Info("delete old successful job", "job", job)
+				}
 			}
 		}
 	}
 
 	// ########################################## //
-	// 4: Check if we are suspended
+	// 4: Check if we are completed, expired or suspended
 	// ########################################## //
+	// A CronJob that made its MaxRuns doesn't run anymore, and may clean up after itself.
+
+	if meta.IsStatusConditionTrue(cronJob.Status.Conditions, batchv1.CompletedCondition) {
+		if cronJob.Spec.CompletionPolicy == batchv1.DeleteOnCompletion {
+			if err := r.Delete(ctx, &cronJob, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+				log.Error(err, "unable to delete completed CronJob")
+				return ctrl.Result{}, err
+			}
+			r.Recorder.Eventf(&cronJob, corev1.EventTypeNormal, "Completed", "Deleted CronJob after %d runs", *cronJob.Spec.MaxRuns)
+			log.V(0).Info("deleted completed cronjob")
+			return ctrl.Result{}, nil
+		}
+		log.V(1).Info("cronjob completed, skipping")
+		return ctrl.Result{}, nil
+	}
+
+	// Once past its end date, a CronJob is done for good: depending on its ExpirePolicy we either
+	// suspend it, so that it's obvious why nothing runs anymore, or get rid of it altogether.
+
+	if cronJob.IsExpired(r.Now()) {
+		if cronJob.Spec.ExpirePolicy == batchv1.DeleteOnExpiry {
+			if err := r.Delete(ctx, &cronJob, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+				log.Error(err, "unable to delete expired CronJob")
+				return ctrl.Result{}, err
+			}
+			r.Recorder.Eventf(&cronJob, corev1.EventTypeNormal, "Expired", "Deleted CronJob after its end at %s",
+				cronJob.Spec.ActiveUntil.Format(time.RFC3339))
+			log.V(0).Info("deleted expired cronjob")
+			return ctrl.Result{}, nil
+		}
+
+		if cronJob.Spec.Suspend == nil || !*cronJob.Spec.Suspend {
+			suspend := true
+			cronJob.Spec.Suspend = &suspend
+			if err := r.Update(ctx, &cronJob); err != nil {
+				log.Error(err, "unable to suspend expired CronJob")
+				return ctrl.Result{}, err
+			}
+			r.Recorder.Eventf(&cronJob, corev1.EventTypeNormal, "Expired", "Suspended CronJob after its end at %s",
+				cronJob.Spec.ActiveUntil.Format(time.RFC3339))
+			log.V(0).Info("suspended expired cronjob")
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// The circuit breaker of the FailurePolicy suspends a CronJob whose runs keep failing. It
+	// closes again once the cool-down is over, or when someone resumes the CronJob by hand.
+	// Only a suspension of its own is lifted after the cool-down: a CronJob that someone
+	// suspended is theirs to resume.
+	if policy := cronJob.Spec.FailurePolicy; policy != nil {
+		suspended := cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend
+		circuit := meta.FindStatusCondition(cronJob.Status.Conditions, batchv1.CircuitOpenCondition)
+		open := circuit != nil && circuit.Status == metav1.ConditionTrue
+
+		switch {
+		case !open && cronJob.Status.ConsecutiveFailures >= policy.MaxConsecutiveFailures:
+			// record that the breaker suspends the CronJob before doing it: should an update
+			// fail, we just go through this again, and know whose suspension it is
+			if !suspended {
+				cronJob.Status.SuspendedByBreaker = true
+				if err := r.Status().Update(ctx, &cronJob); err != nil {
+					log.Error(err, "unable to update CronJob status")
+					return ctrl.Result{}, err
+				}
+				suspend := true
+				cronJob.Spec.Suspend = &suspend
+				if err := r.Update(ctx, &cronJob); err != nil {
+					log.Error(err, "unable to suspend failing CronJob")
+					return ctrl.Result{}, err
+				}
+			}
+			message := fmt.Sprintf("%d runs failed in a row", cronJob.Status.ConsecutiveFailures)
+			if jobs := cronJob.Status.ConsecutiveFailedJobs; len(jobs) > 0 {
+				message += fmt.Sprintf(", the latest with the Jobs %s", strings.Join(jobs, ", "))
+			}
+			meta.SetStatusCondition(&cronJob.Status.Conditions, metav1.Condition{
+				Type:               batchv1.CircuitOpenCondition,
+				Status:             metav1.ConditionTrue,
+				ObservedGeneration: cronJob.Generation,
+				LastTransitionTime: metav1.NewTime(r.Now()),
+				Reason:             "ConsecutiveFailures",
+				Message:            message,
+			})
+			if err := r.Status().Update(ctx, &cronJob); err != nil {
+				log.Error(err, "unable to update CronJob status")
+				return ctrl.Result{}, err
+			}
+			r.Recorder.Eventf(&cronJob, corev1.EventTypeWarning, "CircuitOpen", "Suspended CronJob: %s", message)
+			log.V(0).Info("suspended failing cronjob", "consecutive failures", cronJob.Status.ConsecutiveFailures)
+			if policy.CoolDown != nil && cronJob.Status.SuspendedByBreaker {
+				return ctrl.Result{RequeueAfter: policy.CoolDown.Duration}, nil
+			}
+			return ctrl.Result{}, nil
+
+		case open && suspended && (policy.CoolDown == nil || !cronJob.Status.SuspendedByBreaker):
+			// it's up to someone to resume the CronJob
+
+		case open && suspended && r.Now().Before(circuit.LastTransitionTime.Add(policy.CoolDown.Duration)):
+			closeAt := circuit.LastTransitionTime.Add(policy.CoolDown.Duration)
+			log.V(1).Info("circuit open, skipping", "until", closeAt)
+			return ctrl.Result{RequeueAfter: closeAt.Sub(r.Now())}, nil
+
+		case open:
+			reason, message := "Resumed", "The CronJob was resumed"
+			if suspended {
+				reason, message = "CoolDownPassed", fmt.Sprintf("The CronJob was resumed after a cool-down of %s", policy.CoolDown.Duration)
+				suspend := false
+				cronJob.Spec.Suspend = &suspend
+				if err := r.Update(ctx, &cronJob); err != nil {
+					log.Error(err, "unable to resume CronJob after its cool-down")
+					return ctrl.Result{}, err
+				}
+			}
+			// give the CronJob a fresh start, or the circuit opens again right away
+			cronJob.Status.ConsecutiveFailures = 0
+			cronJob.Status.ConsecutiveFailedJobs = nil
+			cronJob.Status.SuspendedByBreaker = false
+			meta.SetStatusCondition(&cronJob.Status.Conditions, metav1.Condition{
+				Type:               batchv1.CircuitOpenCondition,
+				Status:             metav1.ConditionFalse,
+				ObservedGeneration: cronJob.Generation,
+				LastTransitionTime: metav1.NewTime(r.Now()),
+				Reason:             reason,
+				Message:            message,
+			})
+			if err := r.Status().Update(ctx, &cronJob); err != nil {
+				log.Error(err, "unable to update CronJob status")
+				return ctrl.Result{}, err
+			}
+			r.Recorder.Event(&cronJob, corev1.EventTypeNormal, "CircuitClosed", message)
+			log.V(0).Info("closed circuit of cronjob", "reason", reason)
+		}
+	}
+
 	// If the object is suspended, we don't want to run any jobs, so we'll stop now. This is useful if
 	// something is broken with the job we are running and we want to pause runs to investigate or
 	// putz with the cluster, without deleting the object
@@ -247,6 +628,39 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{}, nil
 	}
 
+	// A suspension with an end date lifts itself: until then we just come back when it ends,
+	// afterwards we forget about it and carry on. The latest run we skipped in the meantime
+	// is a missed run like any other, so the starting deadline decides whether it still
+	// starts. The end of the suspension goes into the status first, since that's where
+	// getNextSchedule picks it up from, however often we have to try.
+	if until := cronJob.Spec.SuspendUntil; until != nil {
+		if cronJob.IsSuspended(r.Now()) {
+			log.V(1).Info("cronjob suspended, skipping", "until", until.Time)
+			return ctrl.Result{RequeueAfter: until.Sub(r.Now())}, nil
+		}
+
+		if last := cronJob.Status.LastSuspendUntil; last == nil || !last.Equal(until) {
+			cronJob.Status.LastSuspendUntil = until.DeepCopy()
+			if err := r.Status().Update(ctx, &cronJob); err != nil {
+				log.Error(err, "unable to update CronJob status")
+				return ctrl.Result{}, err
+			}
+		}
+		reason := cronJob.Spec.SuspendReason
+		cronJob.Spec.SuspendUntil = nil
+		cronJob.Spec.SuspendReason = ""
+		if err := r.Update(ctx, &cronJob); err != nil {
+			log.Error(err, "unable to clear expired suspension")
+			return ctrl.Result{}, err
+		}
+		message := fmt.Sprintf("Suspension ended at %s", until.Format(time.RFC3339))
+		if reason != "" {
+			message += fmt.Sprintf(" (%s)", reason)
+		}
+		r.Recorder.Event(&cronJob, corev1.EventTypeNormal, "Resumed", message)
+		log.V(0).Info("resumed cronjob after suspension", "until", until.Time)
+	}
+
 	// ########################################## //
 	// 5: Get the next scheduled run
 	// ########################################## //
@@ -258,63 +672,9 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		If there are too many missed runs and we don’t have any deadlines set, we’ll bail so that we
 		don’t cause issues on controller restarts or wedges. Otherwise, we’ll just return the missed runs
 		 (of which we’ll just use the latest), and the next run, so that we can know when it’s time to
-		 reconcile again.
+		 reconcile again. getNextSchedule, further down, does all of that.
 	*/
 
-	getNextSchedule := func(cronJob *batchv1.CronJob, now time.Time) (lastMissed time.Time, next time.Time, err error) {
-		sched, err := cron.ParseStandard(cronJob.Spec.Schedule)
-		if err != nil {
-			return time.Time{}, time.Time{}, fmt.Errorf("unparseable schedule %q: %v", cronJob.Spec.Schedule, err)
-		}
-
-		// for optimization purposes, cheat a bit and start from our last observed run time
-		// we could reconstitute this here, but there's not much point, since we've
-		// just updated it.
-
-		var earliestTime time.Time
-		if cronJob.Status.LastScheduleTime != nil {
-			earliestTime = cronJob.Status.LastScheduleTime.Time
-		} else {
-			earliestTime = cronJob.ObjectMeta.CreationTimestamp.Time
-		}
-		if cronJob.Spec.StartingDeadlineSeconds != nil {
-			// controller is not going to schedule anything below this poit
-			schedulingDeadline := now.Add(-time.Second * time.Duration(*cronJob.Spec.StartingDeadlineSeconds))
-
-			if schedulingDeadline.After(earliestTime) {
-				earliestTime = schedulingDeadline
-			}
-
-		}
-		if earliestTime.After(now) {
-			return time.Time{}, sched.Next(now), nil
-		}
-		starts := 0
-		for t := sched.Next(earliestTime); !t.After(now); t = sched.Next(t) {
-			lastMissed = t
-			// An object might miss several starts. For example, if
-			// controller gets wedged on Friday at 5:01pm when everyone has
-			// gone home, and someone comes in on Tuesday AM and discovers
-			// the problem and restarts the controller, then all the hourly
-			// jobs, more than 80 of them for one hourly scheduledJob, should
-			// all start running with no further intervention (if the scheduledJob
-			// allows concurrency and late starts).
-			//
-			// However, if there is a bug somewhere, or incorrect clock
-			// on controller's server or apiservers (for setting creationTimestamp)
-			// then there could be so many missed start times (it could be off
-			// by decades or more), that it would eat up all the CPU and memory
-			// of this controller. In that case, we want to not try to list
-			// all the missed start times.
-			starts++
-			if starts > 100 {
-				return time.Time{}, time.Time{}, fmt.Errorf("too many misssed start times (>100). set or decrease .spec.startingDeadlineSeconds or check clock skew")
-			}
-		}
-
-		return lastMissed, sched.Next(now), nil
-	}
-
 	// Figure out the next times that we need to create
 	// jobs at (or anything we missed).
 
@@ -329,6 +689,10 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	// We'll prep our eventual request to requeue until the next job, and then figure out
 	// if we actually need to run.
 	scheduledResult := ctrl.Result{RequeueAfter: nextRun.Sub(r.Now())} // save this so that we can re-se it elsewhere
+	if until := cronJob.Spec.ActiveUntil; until != nil && until.Time.Before(nextRun) {
+		// come back in time to mark the CronJob expired
+		scheduledResult.RequeueAfter = until.Sub(r.Now())
+	}
 	log = log.WithValues("now", r.Now(), "next run", nextRun)
 
 	// ########################################## //
@@ -337,6 +701,39 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 
 	// Legit job: Is on schedule, not past deadline, not blocked by concurrency policy
 
+	// A tick for which we only got some of the Jobs of the matrix created, e.g. because the API
+	// server had a hiccup halfway through, is caught up on like a missed one. (That includes
+	// the last tick for a newly added entry, if it's still within the starting deadline.)
+	// Entries that were handled at the tick are done with it, though, even if their Job has
+	// been cleaned up since, or they were skipped because of the concurrency policy.
+	existingJobs := make(map[string]bool)
+	for _, job := range childJobs {
+		existingJobs[job.GetName()] = true
+	}
+	if missedRun.IsZero() && len(cronJob.Spec.Matrix) > 0 && cronJob.Status.LastScheduleTime != nil {
+		lastTick := cronJob.Status.LastScheduleTime.Time
+		for _, run := range cronJob.RunsForMatrix(batchv1.Run{ScheduledTime: lastTick, Trigger: batchv1.ScheduledRun, Attempt: 1}) {
+			if isEntryHandled(&cronJob.Status, run.MatrixEntry.Name, lastTick) {
+				continue
+			}
+			if job, err := batchv1.ConstructForCronJobRun(&cronJob, run, r.Scheme); err == nil && !existingJobs[job.GetName()] {
+				missedRun = lastTick
+				break
+			}
+		}
+	}
+
+	// An action that was started but isn't over, e.g. because the status update after it
+	// failed, is finished first, however late that is by now.
+	if running := cronJob.Status.RunningAction(); running != nil && cronJob.HasAction() {
+		retryAt, err := r.runAction(ctx, &cronJob, running.ScheduledTime.Time)
+		if err != nil {
+			log.Error(err, "unable to run action for CronJob")
+			return ctrl.Result{}, err
+		}
+		return actionResult(scheduledResult, retryAt.Sub(r.Now())), nil
+	}
+
 	if missedRun.IsZero() {
 		log.V(1).Info("no upcoming scheduled times, sleeping until next")
 		return scheduledResult, nil
@@ -352,83 +749,132 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 
 	if tooLate {
 		log.V(1).Info("missed starting deadline for last run, sleeping till next")
-		// TODO: Should create events? is that what "TODO(directxman12): events" means?
+		// we'll come across the same missed run until the next one, but only report it once
+		if last := cronJob.Status.LastMissedRunTime; last == nil || last.Time.Before(missedRun) {
+			cronJob.Status.LastMissedRunTime = &metav1.Time{Time: missedRun}
+			if err := r.Status().Update(ctx, &cronJob); err != nil {
+				log.Error(err, "unable to update CronJob status")
+				return ctrl.Result{}, err
+			}
+			r.Recorder.Eventf(&cronJob, corev1.EventTypeWarning, "MissedSchedule", "Missed the starting deadline for the run at %s",
+				missedRun.Format(time.RFC3339))
+			r.notify(ctx, &cronJob, eventNotifications(&cronJob, batchv1.MissedDeadlineEvent, scheduledRun{scheduledTime: missedRun}, r.Now()))
+		}
 		return scheduledResult, nil
 	}
 
-	/*
-		if we actually have to run a job, we'll need to wait till the existing ones finish,
-		replace the existing ones or just add new ones. If our information is out of date
-		due to cache delay, we'll get a requeue when we get up-to-date-information
-	*/
-
-	// figure out how to run this job -- concurrency policy might forbid us from running
-	// multiple at the same time..
-	if cronJob.Spec.ConcurrencyPolicy == batchv1.ForbidConcurrent && len(activeJobs) > 0 {
-		log.V(1).Info("concurrency policy blocks concurrent runs, skipping", "num active", len(activeJobs))
-		return scheduledResult, nil
-	}
-	// or if it instructs us to replace existing
-	if cronJob.Spec.ConcurrencyPolicy == batchv1.ReplaceConcurrent {
+	// The CronJob mustn't overshoot its MaxRuns with the runs that are still going, unless
+	// those are about to be replaced anyway. All Jobs of a tick make up a single run.
+	if cronJob.Spec.MaxRuns != nil {
+		runningTicks := make(map[string]bool)
 		for _, activeJob := range activeJobs {
-			// we don't care if the job was already deleted
-			if err := r.Delete(ctx, activeJob, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
-				log.Error(err, "unable to delete active job", "job", activeJob)
-				return ctrl.Result{}, err
-			}
+			runningTicks[activeJob.GetAnnotations()[batchv1.ScheduledTimeAnnotation]] = true
+		}
+		running := int64(len(runningTicks))
+		if cronJob.Spec.ConcurrencyPolicy == batchv1.ReplaceConcurrent {
+			running = 0
+		}
+		if finishedRuns+running >= int64(*cronJob.Spec.MaxRuns) {
+			log.V(1).Info("active runs may complete the cronjob, skipping", "num active", len(activeJobs), "finished runs", finishedRuns)
+			return scheduledResult, nil
 		}
 	}
 
-	// At this stage we have to proceed ahead with creating the job
+	// An action is carried out right away, and is over as soon as it is, so there is no
+	// concurrency to worry about.
+	if cronJob.HasAction() {
+		retryAt, err := r.runAction(ctx, &cronJob, missedRun)
+		if err != nil {
+			log.Error(err, "unable to run action for CronJob")
+			return ctrl.Result{}, err
+		}
+		return actionResult(scheduledResult, retryAt.Sub(r.Now())), nil
+	}
+
 	/*
-		we need to construct a job based on our CronJob template. We'll copy over the spec from the template
-		and copy some basic object meta.
-		Then, we'll set the "ScheduledTime" annotation so that we can reconstitute our `LastScheduleTime`
-		field each reconcile
-		constructJobForCronJob is the helper function to create the Job object
+		With a matrix, we create one Job per entry; without one, there is just a single run.
+		Each entry is on its own as far as the concurrency policy is concerned.
 	*/
+	activeJobsByEntry := byMatrixEntry(activeJobs)
+	handled := false
+	for _, run := range cronJob.RunsForMatrix(batchv1.Run{ScheduledTime: missedRun, Trigger: batchv1.ScheduledRun, Attempt: 1}) {
+		log := log
+		entry := ""
+		if run.MatrixEntry != nil {
+			entry = run.MatrixEntry.Name
+			log = log.WithValues("matrix entry", entry)
+		}
+		activeJobs := activeJobsByEntry[entry]
 
-	constructJobForCronJob := func(cronJob *batchv1.CronJob, scheduledTime time.Time) (*kbatch.Job, error) {
-		// we want job names for a given nominal start time to have a deterministic name
-		name := fmt.Sprintf("%s-%d", cronJob.Name, scheduledTime.Unix())
+		// At this stage we have to proceed ahead with creating the job
+		/*
+			we need to construct a job based on our CronJob template. We'll copy over the spec from the template
+			and copy some basic object meta.
+			Then, we'll set the "ScheduledTime" annotation so that we can reconstitute our `LastScheduleTime`
+			field each reconcile
+			batchv1.ConstructForCronJobRun is the helper function to create the Job object, or the
+			object of the resource template; it is shared with the validating webhook, which
+			dry-runs the same object at admission
+		*/
+		job, err := batchv1.ConstructForCronJobRun(&cronJob, run, r.Scheme)
+		if err != nil {
+			log.Error(err, "unable to construct job from template")
+			// don't bother requeuing until we get a change in the spec
+			return scheduledResult, nil
+		}
+		if existingJobs[job.GetName()] || entry != "" && isEntryHandled(&cronJob.Status, entry, missedRun) {
+			continue
+		}
+
+		/*
+			if we actually have to run a job, we'll need to wait till the existing ones finish,
+			replace the existing ones or just add new ones. If our information is out of date
+			due to cache delay, we'll get a requeue when we get up-to-date-information
+		*/
 
-		job := &kbatch.Job{
-			ObjectMeta: metav1.ObjectMeta{
-				Labels:      make(map[string]string),
-				Annotations: make(map[string]string),
-				Name:        name,
-				Namespace:   cronJob.Namespace,
-			},
-			Spec: *cronJob.Spec.JobTemplate.Spec.DeepCopy(),
+		// figure out how to run this job -- concurrency policy might forbid us from running
+		// multiple at the same time..
+		if cronJob.Spec.ConcurrencyPolicy == batchv1.ForbidConcurrent && len(activeJobs) > 0 {
+			log.V(1).Info("concurrency policy blocks concurrent runs, skipping", "num active", len(activeJobs))
+			handled = markEntryHandled(&cronJob.Status, entry, missedRun) || handled
+			continue
 		}
-		for k, v := range cronJob.Spec.JobTemplate.Annotations {
-			job.Annotations[k] = v
+		// ..or it allows concurrent runs, but only so many of them
+		if cronJob.Spec.ConcurrencyPolicy == batchv1.AllowConcurrent && cronJob.Spec.MaxConcurrentRuns != nil &&
+			int32(len(activeJobs)) >= *cronJob.Spec.MaxConcurrentRuns {
+			log.V(1).Info("maximum of concurrent runs reached, skipping", "num active", len(activeJobs))
+			handled = markEntryHandled(&cronJob.Status, entry, missedRun) || handled
+			continue
 		}
-		job.Annotations[scheduledTimeAnnnotation] = scheduledTime.Format(time.RFC3339)
-		for k, v := range cronJob.Spec.JobTemplate.Labels {
-			job.Labels[k] = v
+		// or if it instructs us to replace existing
+		if cronJob.Spec.ConcurrencyPolicy == batchv1.ReplaceConcurrent {
+			for _, activeJob := range activeJobs {
+				// we don't care if the job was already deleted
+				if err := r.Delete(ctx, activeJob, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+					log.Error(err, "unable to delete active job", "job", activeJob)
+					return ctrl.Result{}, err
+				}
+			}
 		}
-		if err := ctrl.SetControllerReference(cronJob, job, r.Scheme); err != nil {
-			return nil, err
+
+		// ..and create it on the cluster
+		if err := r.Create(ctx, job); err != nil {
+			log.Error(err, "unable to create Job for CronJob", "job", job)
+			return ctrl.Result{}, err
 		}
-		return job, nil
-	}
-	// actuall make the job..
-	job, err := constructJobForCronJob(&cronJob, missedRun)
-	if err != nil {
-		log.Error(err, "unable to construct job from template")
-		// don't bother requeuing until we get a change in the spec
-		return scheduledResult, nil
-	}
 
-	// ..and create it on the cluster
-	if err := r.Create(ctx, job); err != nil {
-		log.Error(err, "unable to create Job for CronJob", "job")
-		return ctrl.Result{}, err
+		// finally we succeeded to create the job on the cluster..phew!
+		log.V(1).Info("created Job for CronJob run", "job", job)
+		handled = markEntryHandled(&cronJob.Status, entry, missedRun) || handled
 	}
 
-	// finally we succeeded to create the job on the cluster..phew!
-	log.V(1).Info("created Job for CronJob run", "job", job)
+	// The entries we skipped leave no trace but the status, so it had better be saved.
+	if handled {
+		if err := r.Status().Update(ctx, &cronJob); err != nil {
+			log.Error(err, "unable to update CronJob status")
+			return ctrl.Result{}, err
+		}
+	}
 
 	// ##########################################   //
 	// 7: Return Reconcile result   			   //
@@ -437,6 +883,263 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	return scheduledResult, nil
 }
 
+// scheduledRun is a run of a CronJob as far as counting it, and notifying about it, is concerned.
+type scheduledRun struct {
+	scheduledTime time.Time
+	finishedType  kbatch.JobConditionType
+
+	// one of the Jobs of the run that failed, and whether it gave up after all of its retries
+	failedJob        string
+	retriesExhausted bool
+
+	// how long the run took, if we can tell
+	duration time.Duration
+}
+
+/*
+countRuns adds the finished runs that haven't been counted yet to the counts in the
+status, so that counting stays idempotent however often we go over the same Jobs. Each
+run is counted as soon as it finishes: status.LastCountedRunTime marks how far we got,
+and status.UncountedRunTimes keeps the runs behind it that were still going then, so
+that a run that hangs doesn't hold up the ones after it. The Jobs of the matrix entries
+of a tick make up a single run, which is finished once all of them are, succeeded if
+all of them did, and took as long as the longest of them. The runs counted this time
+around are returned, in the order they were scheduled.
+*/
+func countRuns(status *batchv1.CronJobStatus, jobs []scheduledRun) (counted []scheduledRun) {
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].scheduledTime.Before(jobs[j].scheduledTime)
+	})
+	var runs []scheduledRun
+	for _, job := range jobs {
+		if len(runs) == 0 || !runs[len(runs)-1].scheduledTime.Equal(job.scheduledTime) {
+			runs = append(runs, job)
+			continue
+		}
+		run := &runs[len(runs)-1]
+		if run.failedJob == "" {
+			run.failedJob, run.retriesExhausted = job.failedJob, job.retriesExhausted
+		}
+		if job.duration > run.duration {
+			run.duration = job.duration
+		}
+		switch {
+		case run.finishedType == "" || job.finishedType == "":
+			run.finishedType = ""
+		case job.finishedType == kbatch.JobFailed:
+			run.finishedType = kbatch.JobFailed
+		}
+	}
+
+	// what was counted before this time around
+	previous := status.DeepCopy()
+	var stillGoing []time.Time
+	for _, run := range runs {
+		if isRunCounted(previous, run.scheduledTime) {
+			continue
+		}
+		if run.finishedType == "" {
+			stillGoing = append(stillGoing, run.scheduledTime)
+			continue
+		}
+		if run.finishedType == kbatch.JobComplete {
+			status.SucceededRuns++
+		} else {
+			status.FailedRuns++
+		}
+		if status.LastCountedRunTime == nil || run.scheduledTime.After(status.LastCountedRunTime.Time) {
+			status.LastCountedRunTime = &metav1.Time{Time: run.scheduledTime}
+		}
+		counted = append(counted, run)
+	}
+
+	// Runs that went away before they finished, e.g. because they were replaced, are
+	// never going to be counted, so we forget about them.
+	status.UncountedRunTimes = nil
+	for _, t := range stillGoing {
+		if status.LastCountedRunTime != nil && !t.After(status.LastCountedRunTime.Time) {
+			status.UncountedRunTimes = append(status.UncountedRunTimes, metav1.Time{Time: t})
+		}
+	}
+	return counted
+}
+
+// isRunCounted tells whether the run scheduled at t is included in the counts of the status.
+func isRunCounted(status *batchv1.CronJobStatus, t time.Time) bool {
+	if status.LastCountedRunTime == nil || t.After(status.LastCountedRunTime.Time) {
+		return false
+	}
+	for _, uncounted := range status.UncountedRunTimes {
+		if uncounted.Time.Equal(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// trackConsecutiveFailures keeps count of the runs that failed in a row, for the circuit
+// breaker, going over the runs that were just counted.
+func trackConsecutiveFailures(status *batchv1.CronJobStatus, counted []scheduledRun) {
+	for _, run := range counted {
+		if run.finishedType != kbatch.JobFailed {
+			status.ConsecutiveFailures = 0
+			status.ConsecutiveFailedJobs = nil
+			continue
+		}
+		status.ConsecutiveFailures++
+		if run.failedJob != "" {
+			status.ConsecutiveFailedJobs = append(status.ConsecutiveFailedJobs, run.failedJob)
+			if extra := len(status.ConsecutiveFailedJobs) - batchv1.MaxConsecutiveFailedJobs; extra > 0 {
+				status.ConsecutiveFailedJobs = status.ConsecutiveFailedJobs[extra:]
+			}
+		}
+	}
+}
+
+// getUpcomingRuns returns the next scheduled times of the CronJob after now, none if it is
+// suspended, completed or its schedule can't be parsed.
+func getUpcomingRuns(cronJob *batchv1.CronJob, now time.Time) []metav1.Time {
+	if cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend {
+		return nil
+	}
+	if meta.IsStatusConditionTrue(cronJob.Status.Conditions, batchv1.CompletedCondition) {
+		return nil
+	}
+
+	limit := int32(batchv1.DefaultUpcomingRunsLimit)
+	if cronJob.Spec.UpcomingRunsLimit != nil {
+		limit = *cronJob.Spec.UpcomingRunsLimit
+	}
+	if limit > batchv1.MaxUpcomingRunsLimit {
+		limit = batchv1.MaxUpcomingRunsLimit
+	}
+
+	next, err := cronJob.NextRuns(now, int(limit))
+	if err != nil {
+		return nil
+	}
+	var runs []metav1.Time
+	for _, t := range next {
+		runs = append(runs, metav1.Time{Time: t})
+	}
+	return runs
+}
+
+/*
+observeSchedule records the schedule the CronJob works against in its status, and tells
+whether it changed. The first time we see a CronJob we just record it; after that, every
+edit bumps the generation and moves the anchor that the ResetAnchor policy counts missed
+runs from.
+*/
+func observeSchedule(cronJob *batchv1.CronJob, now time.Time) bool {
+	if cronJob.Status.ObservedSchedule == cronJob.Spec.Schedule {
+		return false
+	}
+	if cronJob.Status.ObservedSchedule != "" {
+		cronJob.Status.LastScheduleChangeTime = &metav1.Time{Time: now}
+	}
+	cronJob.Status.ObservedSchedule = cronJob.Spec.Schedule
+	cronJob.Status.ScheduleGeneration++
+	return true
+}
+
+// lastTickBetween returns the latest tick of the schedule after after and not after until,
+// zero if there is none. Cron schedules can't be stepped backwards, so we look at ever
+// longer stretches before until.
+func lastTickBetween(sched cron.Schedule, after, until time.Time) time.Time {
+	for window := time.Hour; ; window *= 2 {
+		from := until.Add(-window)
+		if !from.After(after) {
+			from = after
+		}
+		var last time.Time
+		for t := sched.Next(from); !t.IsZero() && !t.After(until); t = sched.Next(t) {
+			last = t
+		}
+		if !last.IsZero() || from.Equal(after) {
+			return last
+		}
+	}
+}
+
+// getNextSchedule returns the latest run the CronJob missed, if any, and its next run
+// after now. See step 5 of Reconcile.
+func getNextSchedule(cronJob *batchv1.CronJob, now time.Time) (lastMissed time.Time, next time.Time, err error) {
+	sched, err := cron.ParseStandard(cronJob.Spec.Schedule)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("unparseable schedule %q: %v", cronJob.Spec.Schedule, err)
+	}
+
+	// for optimization purposes, cheat a bit and start from our last observed run time
+	// we could reconstitute this here, but there's not much point, since we've
+	// just updated it.
+
+	var earliestTime time.Time
+	if cronJob.Status.LastScheduleTime != nil {
+		earliestTime = cronJob.Status.LastScheduleTime.Time
+	} else {
+		earliestTime = cronJob.ObjectMeta.CreationTimestamp.Time
+	}
+	if cronJob.Spec.ScheduleChangePolicy == batchv1.ResetAnchorScheduleChange && cronJob.Status.LastScheduleChangeTime != nil {
+		// runs the new schedule would have made before it was in place don't count
+		if cronJob.Status.LastScheduleChangeTime.Time.After(earliestTime) {
+			earliestTime = cronJob.Status.LastScheduleChangeTime.Time
+		}
+	}
+	if suspendedUntil := cronJob.Status.LastSuspendUntil; suspendedUntil != nil && suspendedUntil.After(earliestTime) {
+		// Only the latest of the runs a timed suspension skipped is caught up on, so we start
+		// right before it rather than go through all of them, which can be more than we
+		// allow below for a long suspension.
+		if tick := lastTickBetween(sched, earliestTime, suspendedUntil.Time); !tick.IsZero() {
+			earliestTime = tick.Add(-time.Second)
+		} else {
+			earliestTime = suspendedUntil.Time
+		}
+	}
+	if cronJob.Spec.ActiveFrom != nil {
+		// nothing runs before the start of the active range, but a run right at it does
+		if activeFrom := cronJob.Spec.ActiveFrom.Add(-time.Second); activeFrom.After(earliestTime) {
+			earliestTime = activeFrom
+		}
+	}
+	if cronJob.Spec.StartingDeadlineSeconds != nil {
+		// controller is not going to schedule anything below this poit
+		schedulingDeadline := now.Add(-time.Second * time.Duration(*cronJob.Spec.StartingDeadlineSeconds))
+
+		if schedulingDeadline.After(earliestTime) {
+			earliestTime = schedulingDeadline
+		}
+
+	}
+	if earliestTime.After(now) {
+		return time.Time{}, sched.Next(earliestTime), nil
+	}
+	starts := 0
+	for t := sched.Next(earliestTime); !t.After(now); t = sched.Next(t) {
+		lastMissed = t
+		// An object might miss several starts. For example, if
+		// controller gets wedged on Friday at 5:01pm when everyone has
+		// gone home, and someone comes in on Tuesday AM and discovers
+		// the problem and restarts the controller, then all the hourly
+		// jobs, more than 80 of them for one hourly scheduledJob, should
+		// all start running with no further intervention (if the scheduledJob
+		// allows concurrency and late starts).
+		//
+		// However, if there is a bug somewhere, or incorrect clock
+		// on controller's server or apiservers (for setting creationTimestamp)
+		// then there could be so many missed start times (it could be off
+		// by decades or more), that it would eat up all the CPU and memory
+		// of this controller. In that case, we want to not try to list
+		// all the missed start times.
+		starts++
+		if starts > 100 {
+			return time.Time{}, time.Time{}, fmt.Errorf("too many misssed start times (>100). set or decrease .spec.startingDeadlineSeconds or check clock skew")
+		}
+	}
+
+	return lastMissed, sched.Next(now), nil
+}
+
 /*
 Finally, we will update our setup. In order to allow our reconciler to quickly look up
 jobs by their owner, we'll need an index. We declare an index key that we can later use
@@ -452,6 +1155,16 @@ var (
 	apiGVStr    = batchv1.GroupVersion.String()
 )
 
+// NewRateLimiter returns the same rate limiter as workqueue.DefaultControllerRateLimiter,
+// with the parameters exposed: an exponential back-off per CronJob, capped by an overall
+// token bucket.
+func NewRateLimiter(baseDelay, maxDelay time.Duration, qps float64, burst int) ratelimiter.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(baseDelay, maxDelay),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(qps), burst)},
+	)
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *CronJobReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	// set up a real Clock, since we are not in a test
@@ -459,24 +1172,226 @@ func (r *CronJobReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		r.Clock = realClock{}
 	}
 
-	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &kbatch.Job{}, jobOwnerKey, func(rawObj client.Object) []string {
+	if err := indexJobsByOwner(mgr, jobOwnerKey, "CronJob"); err != nil {
+		return err
+	}
+
+	bldr := ctrl.NewControllerManagedBy(mgr).
+		For(&batchv1.CronJob{}).
+		Owns(&kbatch.Job{}).
+		WithEventFilter(inNamespaces(r.WatchNamespaces)).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+			RateLimiter:             r.RateLimiter,
+		})
+	if r.Shards != nil {
+		bldr = bldr.Watches(r.Shards.Source(), &handler.EnqueueRequestForObject{})
+	}
+	c, err := bldr.Build(r)
+	if err != nil {
+		return err
+	}
+	r.controller = c
+	return nil
+}
+
+/*
+watchResource makes sure we watch the objects of the kind of the resource template, so
+that we hear about them finishing just like we do about Jobs. We can't know the kinds up
+front, so each one is watched from the first CronJob that uses it on, for as long as the
+manager runs. The manager has to be allowed to list and watch them, of course.
+*/
+func (r *CronJobReconciler) watchResource(template *batchv1.ResourceTemplate) error {
+	gvk, err := template.GroupVersionKind()
+	if err != nil {
+		return err
+	}
+
+	r.watchedMu.Lock()
+	defer r.watchedMu.Unlock()
+	if r.watched[gvk] || r.controller == nil {
+		return nil
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	if err := r.controller.Watch(&source.Kind{Type: obj},
+		&handler.EnqueueRequestForOwner{OwnerType: &batchv1.CronJob{}, IsController: true},
+		inNamespaces(r.WatchNamespaces)); err != nil {
+		return err
+	}
+	if r.watched == nil {
+		r.watched = make(map[schema.GroupVersionKind]bool)
+	}
+	r.watched[gvk] = true
+	return nil
+}
+
+// listChildren returns the Jobs of the CronJob, or the objects it created from its resource
+// template. The client doesn't cache unstructured objects, so the latter come straight from
+// the API server, selected by label.
+func (r *CronJobReconciler) listChildren(ctx context.Context, cronJob *batchv1.CronJob) ([]client.Object, error) {
+	var children []client.Object
+	if cronJob.Spec.ResourceTemplate == nil {
+		var childJobs kbatch.JobList
+		if err := r.List(ctx, &childJobs, client.InNamespace(cronJob.Namespace), client.MatchingFields{jobOwnerKey: cronJob.Name}); err != nil {
+			return nil, err
+		}
+		for i := range childJobs.Items {
+			children = append(children, &childJobs.Items[i])
+		}
+		return children, nil
+	}
+
+	gvk, err := cronJob.Spec.ResourceTemplate.GroupVersionKind()
+	if err != nil {
+		return nil, err
+	}
+	var list unstructured.UnstructuredList
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := r.List(ctx, &list, client.InNamespace(cronJob.Namespace), client.MatchingLabels{batchv1.CronJobLabel: cronJob.Name}); err != nil {
+		return nil, err
+	}
+	for i := range list.Items {
+		// the label is easily copied, the controller reference isn't
+		if metav1.IsControlledBy(&list.Items[i], cronJob) {
+			children = append(children, &list.Items[i])
+		}
+	}
+	return children, nil
+}
+
+// Job is "finished" if it has a "Complete" or "Failed" condition marked as true. Status
+// conditions allow us to add extensible status information to our objects that other humans and
+// controllers can examine to check things like completion and health
+func isJobFinished(job *kbatch.Job) (bool, kbatch.JobConditionType) {
+	for _, c := range job.Status.Conditions {
+		if (c.Type == kbatch.JobComplete || c.Type == kbatch.JobFailed) && c.Status == corev1.ConditionTrue {
+			return true, c.Type
+		}
+	}
+	return false, ""
+}
+
+// jobFinishedType tells how a job of the CronJob finished, if it did: a Job by its conditions,
+// an object of a resource template by the completion conditions of the template.
+func jobFinishedType(cronJob *batchv1.CronJob, job client.Object) kbatch.JobConditionType {
+	var finishedType kbatch.JobConditionType
+	switch job := job.(type) {
+	case *kbatch.Job:
+		_, finishedType = isJobFinished(job)
+	case *unstructured.Unstructured:
+		if cronJob.Spec.ResourceTemplate != nil {
+			_, finishedType = cronJob.Spec.ResourceTemplate.IsFinished(job)
+		}
+	}
+	return finishedType
+}
+
+// jobRetriesExhausted tells whether the job is a Job that failed because it reached its
+// backoff limit.
+func jobRetriesExhausted(job client.Object) bool {
+	if job, ok := job.(*kbatch.Job); ok {
+		for _, c := range job.Status.Conditions {
+			if c.Type == kbatch.JobFailed && c.Status == corev1.ConditionTrue && c.Reason == "BackoffLimitExceeded" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jobStartTime returns when a job started: the start time of a Job, the creation time of
+// any other object.
+func jobStartTime(job client.Object) *metav1.Time {
+	if job, ok := job.(*kbatch.Job); ok {
+		return job.Status.StartTime
+	}
+	created := job.GetCreationTimestamp()
+	return &created
+}
+
+// indexJobsByOwner indexes the Jobs under key by the name of their controller, as long as
+// that is one of our kind.
+func indexJobsByOwner(mgr ctrl.Manager, key, kind string) error {
+	return mgr.GetFieldIndexer().IndexField(context.Background(), &kbatch.Job{}, key, jobOwnerIndexer(kind))
+}
+
+// jobOwnerIndexer extracts the name of the controller of a Job, as long as it is of the kind.
+func jobOwnerIndexer(kind string) client.IndexerFunc {
+	return func(rawObj client.Object) []string {
 		// grab the Job object, extract the Owner..
 		job := rawObj.(*kbatch.Job)
 		owner := metav1.GetControllerOf(job)
 		if owner == nil {
 			return nil
 		}
-		// make sure it's a CronJob..
-		if owner.APIVersion != apiGVStr || owner.Kind != "CronJob" {
+		// make sure it's ours..
+		if owner.APIVersion != apiGVStr || owner.Kind != kind {
 			return nil
 		}
 		// ..and if it is, return it
 		return []string{owner.Name}
-	}); err != nil {
-		return err
 	}
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&batchv1.CronJob{}).
-		Owns(&kbatch.Job{}).
-		Complete(r)
+}
+
+// inNamespaces filters out objects outside of the given namespaces, if any. The cache should
+// only hold objects of the watched namespaces already, but we don't want to rely on how the
+// manager was put together.
+func inNamespaces(namespaces []string) predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		if len(namespaces) == 0 {
+			return true
+		}
+		for _, ns := range namespaces {
+			if obj.GetNamespace() == ns {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// actionResult comes back for the retry of an action, if it's due before the next run.
+func actionResult(scheduledResult ctrl.Result, retryAfter time.Duration) ctrl.Result {
+	if retryAfter > 0 && (scheduledResult.RequeueAfter == 0 || retryAfter < scheduledResult.RequeueAfter) {
+		scheduledResult.RequeueAfter = retryAfter
+	}
+	return scheduledResult
+}
+
+// isEntryHandled tells whether the matrix entry got a Job for the tick at t, or was skipped
+// at it on purpose.
+func isEntryHandled(status *batchv1.CronJobStatus, entry string, t time.Time) bool {
+	for _, entryStatus := range status.Matrix {
+		if entryStatus.Name == entry {
+			return entryStatus.LastHandledTime != nil && !entryStatus.LastHandledTime.Time.Before(t)
+		}
+	}
+	return false
+}
+
+// markEntryHandled records that the matrix entry is done with the tick at t, and tells
+// whether that's news.
+func markEntryHandled(status *batchv1.CronJobStatus, entry string, t time.Time) bool {
+	for i := range status.Matrix {
+		if entryStatus := &status.Matrix[i]; entryStatus.Name == entry {
+			if entryStatus.LastHandledTime != nil && !entryStatus.LastHandledTime.Time.Before(t) {
+				return false
+			}
+			entryStatus.LastHandledTime = &metav1.Time{Time: t}
+			return true
+		}
+	}
+	return false
+}
+
+// byMatrixEntry groups the Jobs by the matrix entry they were created for. Jobs of CronJobs
+// without a matrix all end up under "".
+func byMatrixEntry(jobs []client.Object) map[string][]client.Object {
+	byEntry := make(map[string][]client.Object)
+	for _, job := range jobs {
+		entry := job.GetLabels()[batchv1.MatrixEntryLabel]
+		byEntry[entry] = append(byEntry[entry], job)
+	}
+	return byEntry
 }
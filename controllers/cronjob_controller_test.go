@@ -0,0 +1,685 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/robfig/cron"
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	batchv1 "tutorial.kubebuilder.io/project/api/v1"
+)
+
+// newTestScheme returns a scheme with both the built-in kinds and ours.
+func newTestScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := batchv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return scheme
+}
+
+// newTestCronJobReconciler returns a reconciler at now, backed by a fake client with objs.
+func newTestCronJobReconciler(t *testing.T, scheme *runtime.Scheme, now time.Time, objs ...client.Object) (*CronJobReconciler, client.Client) {
+	t.Helper()
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).
+		WithIndex(&kbatch.Job{}, jobOwnerKey, jobOwnerIndexer("CronJob")).Build()
+	return &CronJobReconciler{
+		Client:   c,
+		Scheme:   scheme,
+		Clock:    &fakeClock{now: now},
+		Recorder: record.NewFakeRecorder(100),
+	}, c
+}
+
+// newTestJob returns the Job the CronJob runs at scheduledTime, for the matrix entry if
+// there is one, finished as finishedType unless that's empty.
+func newTestJob(t *testing.T, scheme *runtime.Scheme, cronJob *batchv1.CronJob, scheduledTime time.Time, entry string, finishedType kbatch.JobConditionType) *kbatch.Job {
+	t.Helper()
+	run := batchv1.Run{ScheduledTime: scheduledTime, Trigger: batchv1.ScheduledRun, Attempt: 1}
+	for i := range cronJob.Spec.Matrix {
+		if cronJob.Spec.Matrix[i].Name == entry {
+			run.MatrixEntry = &cronJob.Spec.Matrix[i]
+		}
+	}
+	job, err := batchv1.ConstructJobForCronJobRun(cronJob, run, scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.CreationTimestamp = metav1.Time{Time: scheduledTime}
+	job.Status.StartTime = &metav1.Time{Time: scheduledTime}
+	if finishedType != "" {
+		job.Status.Conditions = []kbatch.JobCondition{{Type: finishedType, Status: corev1.ConditionTrue}}
+	}
+	return job
+}
+
+func TestCountRuns(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	run := func(hour int, finishedType kbatch.JobConditionType) scheduledRun {
+		return scheduledRun{scheduledTime: start.Add(time.Duration(hour) * time.Hour), finishedType: finishedType}
+	}
+	var status batchv1.CronJobStatus
+
+	// the run at 2 is still going, which doesn't hold up the ones after it
+	jobs := []scheduledRun{
+		run(3, kbatch.JobFailed), run(0, kbatch.JobComplete), run(1, kbatch.JobComplete), run(2, ""), run(4, kbatch.JobComplete),
+	}
+	counted := countRuns(&status, jobs)
+	if status.SucceededRuns != 3 || status.FailedRuns != 1 || len(counted) != 4 {
+		t.Fatalf("got %d succeeded, %d failed, %d counted; want 3, 1, 4", status.SucceededRuns, status.FailedRuns, len(counted))
+	}
+	for i, want := range []int{0, 1, 3, 4} {
+		if !counted[i].scheduledTime.Equal(start.Add(time.Duration(want) * time.Hour)) {
+			t.Fatalf("counted %v, want the runs at 0, 1, 3 and 4 in order", counted)
+		}
+	}
+	if !status.LastCountedRunTime.Time.Equal(start.Add(4*time.Hour)) || len(status.UncountedRunTimes) != 1 ||
+		!status.UncountedRunTimes[0].Time.Equal(start.Add(2*time.Hour)) {
+		t.Fatalf("counted up to %s except %v, want up to 4 except 2", status.LastCountedRunTime, status.UncountedRunTimes)
+	}
+
+	// so every finished Job can go
+	for _, job := range []scheduledRun{run(0, ""), run(1, ""), run(3, ""), run(4, "")} {
+		if !isRunCounted(&status, job.scheduledTime) {
+			t.Errorf("the run at %s isn't counted", job.scheduledTime)
+		}
+	}
+	if isRunCounted(&status, start.Add(2*time.Hour)) || isRunCounted(&status, start.Add(5*time.Hour)) {
+		t.Error("the runs at 2 or 5 are counted")
+	}
+
+	// going over the same Jobs again doesn't count anything twice
+	if counted := countRuns(&status, jobs); len(counted) != 0 || status.SucceededRuns != 3 || status.FailedRuns != 1 {
+		t.Fatalf("recounting counted %v, changed the counts to %d succeeded, %d failed", counted, status.SucceededRuns, status.FailedRuns)
+	}
+
+	// the counted runs are pruned, and the one at 2 finishes late
+	jobs = []scheduledRun{run(2, kbatch.JobFailed), run(5, kbatch.JobComplete)}
+	counted = countRuns(&status, jobs)
+	if status.SucceededRuns != 4 || status.FailedRuns != 2 || len(counted) != 2 || counted[0].finishedType != kbatch.JobFailed {
+		t.Fatalf("got %d succeeded, %d failed, counted %v; want 4, 2 and the runs at 2 and 5", status.SucceededRuns, status.FailedRuns, counted)
+	}
+	if !status.LastCountedRunTime.Time.Equal(start.Add(5*time.Hour)) || len(status.UncountedRunTimes) != 0 {
+		t.Fatalf("counted up to %s except %v, want up to 5", status.LastCountedRunTime, status.UncountedRunTimes)
+	}
+
+	// a run that goes away unfinished, e.g. replaced, is forgotten
+	countRuns(&status, []scheduledRun{run(6, ""), run(7, kbatch.JobComplete)})
+	countRuns(&status, []scheduledRun{run(7, kbatch.JobComplete)})
+	if len(status.UncountedRunTimes) != 0 || status.SucceededRuns != 5 {
+		t.Fatalf("got %d succeeded, uncounted %v after the run at 6 went away; want 5, none", status.SucceededRuns, status.UncountedRunTimes)
+	}
+}
+
+func TestCountRunsOfMatrix(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	tick := func(hour int) time.Time { return start.Add(time.Duration(hour) * time.Hour) }
+	var status batchv1.CronJobStatus
+
+	// the Jobs of the entries of a tick count as a single run, which failed if any of them did
+	jobs := []scheduledRun{
+		{scheduledTime: tick(0), finishedType: kbatch.JobComplete}, {scheduledTime: tick(0), finishedType: kbatch.JobComplete},
+		{scheduledTime: tick(1), finishedType: kbatch.JobComplete}, {scheduledTime: tick(1), finishedType: kbatch.JobFailed, failedJob: "b"},
+		{scheduledTime: tick(2), finishedType: kbatch.JobComplete}, {scheduledTime: tick(2), finishedType: ""},
+	}
+	counted := countRuns(&status, jobs)
+	if status.SucceededRuns != 1 || status.FailedRuns != 1 || !status.LastCountedRunTime.Time.Equal(tick(1)) {
+		t.Fatalf("got %d succeeded, %d failed up to %s; want 1, 1 up to %s",
+			status.SucceededRuns, status.FailedRuns, status.LastCountedRunTime.Time, tick(1))
+	}
+	if len(counted) != 2 || counted[1].failedJob != "b" {
+		t.Fatalf("counted %v, want the failed Job of the run at 1 to be b", counted)
+	}
+}
+
+func TestTrackConsecutiveFailures(t *testing.T) {
+	failed := func(job string) scheduledRun {
+		return scheduledRun{finishedType: kbatch.JobFailed, failedJob: job}
+	}
+	var status batchv1.CronJobStatus
+
+	trackConsecutiveFailures(&status, []scheduledRun{failed("a"), {finishedType: kbatch.JobComplete}, failed("b"), failed("")})
+	trackConsecutiveFailures(&status, []scheduledRun{failed("c")})
+	if status.ConsecutiveFailures != 3 || !reflect.DeepEqual(status.ConsecutiveFailedJobs, []string{"b", "c"}) {
+		t.Fatalf("got %d failures in a row with Jobs %v, want 3 with Jobs [b c]", status.ConsecutiveFailures, status.ConsecutiveFailedJobs)
+	}
+
+	trackConsecutiveFailures(&status, []scheduledRun{{finishedType: kbatch.JobComplete}})
+	if status.ConsecutiveFailures != 0 || status.ConsecutiveFailedJobs != nil {
+		t.Fatalf("a success left %d failures in a row with Jobs %v", status.ConsecutiveFailures, status.ConsecutiveFailedJobs)
+	}
+}
+
+func TestObserveSchedule(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	cronJob := &batchv1.CronJob{Spec: batchv1.CronJobSpec{Schedule: "0 * * * *"}}
+
+	// the first schedule we see isn't a change
+	if !observeSchedule(cronJob, now) || cronJob.Status.ScheduleGeneration != 1 || cronJob.Status.LastScheduleChangeTime != nil {
+		t.Errorf("first observeSchedule() left %+v, want generation 1 and no change time", cronJob.Status)
+	}
+	if observeSchedule(cronJob, now.Add(time.Hour)) || cronJob.Status.ScheduleGeneration != 1 {
+		t.Errorf("observeSchedule() of the same schedule left %+v, want no change", cronJob.Status)
+	}
+
+	cronJob.Spec.Schedule = "30 * * * *"
+	later := now.Add(2 * time.Hour)
+	if !observeSchedule(cronJob, later) || cronJob.Status.ScheduleGeneration != 2 ||
+		cronJob.Status.LastScheduleChangeTime == nil || !cronJob.Status.LastScheduleChangeTime.Time.Equal(later) {
+		t.Errorf("observeSchedule() of a new schedule left %+v, want generation 2 changed at %s", cronJob.Status, later)
+	}
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	report := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "report"}}
+	backup := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "backup"}}
+
+	t.Run("backs off per CronJob", func(t *testing.T) {
+		limiter := NewRateLimiter(5*time.Millisecond, 20*time.Millisecond, 1000, 1000)
+		for i, want := range []time.Duration{5 * time.Millisecond, 10 * time.Millisecond, 20 * time.Millisecond, 20 * time.Millisecond} {
+			if got := limiter.When(report); got != want {
+				t.Errorf("retry %d: When() = %s, want %s", i+1, got, want)
+			}
+		}
+		if got := limiter.NumRequeues(report); got != 4 {
+			t.Errorf("NumRequeues() = %d, want 4", got)
+		}
+		// other CronJobs start afresh, and so does a CronJob that succeeded
+		if got := limiter.When(backup); got != 5*time.Millisecond {
+			t.Errorf("When() of another CronJob = %s, want 5ms", got)
+		}
+		limiter.Forget(report)
+		if got := limiter.When(report); got != 5*time.Millisecond {
+			t.Errorf("When() after Forget() = %s, want 5ms", got)
+		}
+	})
+
+	t.Run("caps the overall rate", func(t *testing.T) {
+		limiter := NewRateLimiter(time.Millisecond, time.Millisecond, 1, 1)
+		if got := limiter.When(report); got != time.Millisecond {
+			t.Errorf("When() = %s, want 1ms", got)
+		}
+		// the burst is used up, so the next retry of any CronJob waits for the bucket to refill
+		if got := limiter.When(backup); got < 500*time.Millisecond || got > time.Second {
+			t.Errorf("When() past the burst = %s, want about 1s", got)
+		}
+	})
+}
+
+func TestInNamespaces(t *testing.T) {
+	inNamespace := func(ns string) client.Object {
+		return &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "report"}}
+	}
+	for name, tc := range map[string]struct {
+		namespaces []string
+		namespace  string
+		want       bool
+	}{
+		"all namespaces":     {nil, "team-a", true},
+		"watched namespace":  {[]string{"team-b", "team-a"}, "team-a", true},
+		"other namespace":    {[]string{"team-b", "team-c"}, "team-a", false},
+		"namespace prefixed": {[]string{"team"}, "team-a", false},
+	} {
+		p := inNamespaces(tc.namespaces)
+		obj := inNamespace(tc.namespace)
+		for kind, got := range map[string]bool{
+			"create":  p.Create(event.CreateEvent{Object: obj}),
+			"update":  p.Update(event.UpdateEvent{ObjectOld: obj, ObjectNew: obj}),
+			"delete":  p.Delete(event.DeleteEvent{Object: obj}),
+			"generic": p.Generic(event.GenericEvent{Object: obj}),
+		} {
+			if got != tc.want {
+				t.Errorf("%s: %s = %t, want %t", name, kind, got, tc.want)
+			}
+		}
+	}
+}
+
+func TestGetUpcomingRuns(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 30, 0, 0, time.UTC)
+	at := func(day, hour int) *metav1.Time {
+		return &metav1.Time{Time: time.Date(2023, 1, day, hour, 0, 0, 0, time.UTC)}
+	}
+	hours := func(from *metav1.Time, n int) []metav1.Time {
+		var runs []metav1.Time
+		for i := 0; i < n; i++ {
+			runs = append(runs, metav1.Time{Time: from.Add(time.Duration(i) * time.Hour)})
+		}
+		return runs
+	}
+	completed := func(status metav1.ConditionStatus) batchv1.CronJobStatus {
+		return batchv1.CronJobStatus{Conditions: []metav1.Condition{{Type: batchv1.CompletedCondition, Status: status}}}
+	}
+	limit := func(n int32) *int32 { return &n }
+	suspend := true
+
+	for name, tc := range map[string]struct {
+		spec   batchv1.CronJobSpec
+		status batchv1.CronJobStatus
+		want   []metav1.Time
+	}{
+		"default limit":       {spec: batchv1.CronJobSpec{Schedule: "0 * * * *"}, want: hours(at(1, 13), 3)},
+		"limit":               {spec: batchv1.CronJobSpec{Schedule: "0 * * * *", UpcomingRunsLimit: limit(5)}, want: hours(at(1, 13), 5)},
+		"limit clamped":       {spec: batchv1.CronJobSpec{Schedule: "0 * * * *", UpcomingRunsLimit: limit(100)}, want: hours(at(1, 13), batchv1.MaxUpcomingRunsLimit)},
+		"no runs":             {spec: batchv1.CronJobSpec{Schedule: "0 * * * *", UpcomingRunsLimit: limit(0)}},
+		"suspended":           {spec: batchv1.CronJobSpec{Schedule: "0 * * * *", Suspend: &suspend}},
+		"suspended until":     {spec: batchv1.CronJobSpec{Schedule: "0 * * * *", SuspendUntil: at(2, 9)}, want: hours(at(2, 9), 3)},
+		"not active yet":      {spec: batchv1.CronJobSpec{Schedule: "0 * * * *", ActiveFrom: at(3, 0)}, want: hours(at(3, 0), 3)},
+		"active until":        {spec: batchv1.CronJobSpec{Schedule: "0 * * * *", ActiveUntil: at(1, 15)}, want: hours(at(1, 13), 2)},
+		"expired":             {spec: batchv1.CronJobSpec{Schedule: "0 * * * *", ActiveUntil: at(1, 0)}},
+		"completed":           {spec: batchv1.CronJobSpec{Schedule: "0 * * * *", MaxRuns: limit(1)}, status: completed(metav1.ConditionTrue)},
+		"runs remaining":      {spec: batchv1.CronJobSpec{Schedule: "0 * * * *", MaxRuns: limit(2)}, status: completed(metav1.ConditionFalse), want: hours(at(1, 13), 3)},
+		"unparsable schedule": {spec: batchv1.CronJobSpec{Schedule: "every hour"}},
+	} {
+		cronJob := &batchv1.CronJob{Spec: tc.spec, Status: tc.status}
+		if got := getUpcomingRuns(cronJob, now); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: getUpcomingRuns() = %v, want %v", name, got, tc.want)
+		}
+	}
+}
+
+func TestGetNextSchedule(t *testing.T) {
+	at := func(hour, minute int) time.Time { return time.Date(2023, 1, 1, hour, minute, 0, 0, time.UTC) }
+	metaTime := func(t time.Time) *metav1.Time { return &metav1.Time{Time: t} }
+	now := at(12, 10)
+
+	for name, tc := range map[string]struct {
+		policy         batchv1.ScheduleChangePolicy
+		lastSchedule   *metav1.Time
+		scheduleChange *metav1.Time
+		wantMissed     time.Time
+	}{
+		"on time": {lastSchedule: metaTime(at(12, 0))},
+		"missed":  {lastSchedule: metaTime(at(10, 0)), wantMissed: at(12, 0)},
+		"honored": {policy: batchv1.HonorScheduleChange, lastSchedule: metaTime(at(10, 0)), scheduleChange: metaTime(at(12, 5)), wantMissed: at(12, 0)},
+		"reset":   {policy: batchv1.ResetAnchorScheduleChange, lastSchedule: metaTime(at(10, 0)), scheduleChange: metaTime(at(12, 5))},
+		"reset before the last run": {
+			policy: batchv1.ResetAnchorScheduleChange, lastSchedule: metaTime(at(11, 0)), scheduleChange: metaTime(at(10, 30)), wantMissed: at(12, 0),
+		},
+	} {
+		cronJob := &batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.Time{Time: at(0, 0)}},
+			Spec:       batchv1.CronJobSpec{Schedule: "0 * * * *", ScheduleChangePolicy: tc.policy},
+			Status:     batchv1.CronJobStatus{LastScheduleTime: tc.lastSchedule, LastScheduleChangeTime: tc.scheduleChange},
+		}
+		missed, next, err := getNextSchedule(cronJob, now)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !missed.Equal(tc.wantMissed) || !next.Equal(at(13, 0)) {
+			t.Errorf("%s: getNextSchedule() = %s, %s; want %s, %s", name, missed, next, tc.wantMissed, at(13, 0))
+		}
+	}
+
+	// a CronJob that has been stuck for ages gives up rather than catching up on every run
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.Time{Time: at(0, 0).AddDate(0, 0, -7)}},
+		Spec:       batchv1.CronJobSpec{Schedule: "0 * * * *"},
+	}
+	if _, _, err := getNextSchedule(cronJob, now); err == nil {
+		t.Error("getNextSchedule() didn't give up on more than 100 missed runs")
+	}
+
+	// unless they were skipped by a timed suspension, of which only the latest run counts
+	cronJob.Status.LastSuspendUntil = metaTime(at(11, 30))
+	if missed, _, err := getNextSchedule(cronJob, now); err != nil || !missed.Equal(at(12, 0)) {
+		t.Errorf("getNextSchedule() after a long suspension = %s, %v; want %s", missed, err, at(12, 0))
+	}
+	cronJob.Status.LastSuspendUntil = metaTime(at(12, 5))
+	if missed, _, err := getNextSchedule(cronJob, now); err != nil || !missed.Equal(at(12, 0)) {
+		t.Errorf("getNextSchedule() after a long suspension = %s, %v; want %s", missed, err, at(12, 0))
+	}
+}
+
+func TestLastTickBetween(t *testing.T) {
+	at := func(day, hour, minute int) time.Time { return time.Date(2023, 1, day, hour, minute, 0, 0, time.UTC) }
+	for name, tc := range map[string]struct {
+		schedule     string
+		after, until time.Time
+		want         time.Time
+	}{
+		"hourly":           {"0 * * * *", at(1, 0, 0), at(5, 11, 30), at(5, 11, 0)},
+		"right at until":   {"0 * * * *", at(1, 0, 0), at(5, 11, 0), at(5, 11, 0)},
+		"weekly":           {"0 0 * * MON", at(1, 0, 0), at(20, 12, 0), at(16, 0, 0)},
+		"none in between":  {"0 0 * * MON", at(3, 0, 0), at(8, 0, 0), time.Time{}},
+		"after is no tick": {"0 * * * *", at(5, 11, 0), at(5, 11, 30), time.Time{}},
+	} {
+		sched, err := cron.ParseStandard(tc.schedule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := lastTickBetween(sched, tc.after, tc.until); !got.Equal(tc.want) {
+			t.Errorf("%s: lastTickBetween() = %s, want %s", name, got, tc.want)
+		}
+	}
+}
+
+func TestReconcileSuspendUntil(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	at := func(hour, minute int) time.Time { return time.Date(2023, 1, 6, hour, minute, 0, 0, time.UTC) }
+	key := types.NamespacedName{Namespace: "default", Name: "backup"}
+	// suspended for five days, which is more than 100 hourly runs
+	newCronJob := func(startingDeadlineSeconds *int64) *batchv1.CronJob {
+		return &batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name, UID: "uid", CreationTimestamp: metav1.Time{Time: at(0, 0).AddDate(0, 0, -10)}},
+			Spec: batchv1.CronJobSpec{
+				Schedule:                "0 * * * *",
+				StartingDeadlineSeconds: startingDeadlineSeconds,
+				SuspendUntil:            &metav1.Time{Time: at(11, 30)},
+				SuspendReason:           "maintenance",
+				JobTemplate: kbatch.JobTemplateSpec{Spec: kbatch.JobSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "backup", Image: "backup"}},
+				}}}},
+			},
+			Status: batchv1.CronJobStatus{LastScheduleTime: &metav1.Time{Time: at(11, 0).AddDate(0, 0, -5)}},
+		}
+	}
+	reconcile := func(r *CronJobReconciler, now time.Time) ctrl.Result {
+		t.Helper()
+		r.Clock = &fakeClock{now: now}
+		result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		if err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+		return result
+	}
+	scheduledJobs := func(c client.Client) []string {
+		t.Helper()
+		var jobs kbatch.JobList
+		if err := c.List(ctx, &jobs, client.InNamespace(key.Namespace)); err != nil {
+			t.Fatal(err)
+		}
+		var scheduled []string
+		for _, job := range jobs.Items {
+			scheduled = append(scheduled, job.Annotations[batchv1.ScheduledTimeAnnotation])
+		}
+		return scheduled
+	}
+	events := func(r *CronJobReconciler) []string {
+		var events []string
+		for recorder := r.Recorder.(*record.FakeRecorder); len(recorder.Events) > 0; {
+			events = append(events, <-recorder.Events)
+		}
+		return events
+	}
+
+	t.Run("resumes and catches up on the latest run", func(t *testing.T) {
+		r, c := newTestCronJobReconciler(t, scheme, at(11, 0), newCronJob(nil))
+
+		if result := reconcile(r, at(11, 0)); result.RequeueAfter != 30*time.Minute {
+			t.Errorf("requeued after %s while suspended, want the 30m until the end of the suspension", result.RequeueAfter)
+		}
+		if jobs := scheduledJobs(c); len(jobs) != 0 {
+			t.Fatalf("ran %v while suspended", jobs)
+		}
+
+		result := reconcile(r, at(11, 40))
+		var got batchv1.CronJob
+		if err := c.Get(ctx, key, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Spec.SuspendUntil != nil || got.Spec.SuspendReason != "" {
+			t.Errorf("suspension left at %v (%q) after it ended", got.Spec.SuspendUntil, got.Spec.SuspendReason)
+		}
+		if got.Status.LastSuspendUntil == nil || !got.Status.LastSuspendUntil.Time.Equal(at(11, 30)) {
+			t.Errorf("status.lastSuspendUntil = %v, want %s", got.Status.LastSuspendUntil, at(11, 30))
+		}
+		want := "Normal Resumed Suspension ended at 2023-01-06T11:30:00Z (maintenance)"
+		if events := events(r); len(events) == 0 || events[0] != want {
+			t.Errorf("events %q, want %q first", events, want)
+		}
+		if jobs := scheduledJobs(c); len(jobs) != 1 || jobs[0] != at(11, 0).Format(time.RFC3339) {
+			t.Errorf("ran %v after the suspension, want the run at 11:00 only", jobs)
+		}
+		if result.RequeueAfter != 20*time.Minute {
+			t.Errorf("requeued after %s, want the 20m until the next run", result.RequeueAfter)
+		}
+	})
+
+	t.Run("starting deadline", func(t *testing.T) {
+		deadline := int64(600)
+		r, c := newTestCronJobReconciler(t, scheme, at(11, 40), newCronJob(&deadline))
+		reconcile(r, at(11, 40))
+		if jobs := scheduledJobs(c); len(jobs) != 0 {
+			t.Errorf("ran %v after the suspension, want none past the starting deadline", jobs)
+		}
+	})
+}
+
+func TestReconcileMatrixCatchUp(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	at := func(hour, minute int) time.Time { return time.Date(2023, 1, 1, hour, minute, 0, 0, time.UTC) }
+	key := types.NamespacedName{Namespace: "default", Name: "backup"}
+	newCronJob := func(mutate func(*batchv1.CronJobSpec)) *batchv1.CronJob {
+		cronJob := &batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name, UID: "uid", CreationTimestamp: metav1.Time{Time: at(10, 30)}},
+			Spec: batchv1.CronJobSpec{
+				Schedule: "0 * * * *",
+				Matrix:   []batchv1.MatrixEntry{{Name: "eu"}, {Name: "us"}},
+				JobTemplate: kbatch.JobTemplateSpec{Spec: kbatch.JobSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "backup", Image: "backup"}},
+				}}}},
+			},
+		}
+		mutate(&cronJob.Spec)
+		return cronJob
+	}
+	reconcile := func(r *CronJobReconciler, now time.Time) {
+		t.Helper()
+		r.Clock = &fakeClock{now: now}
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+	}
+	hasJob := func(c client.Client, scheduledTime time.Time, entry string) bool {
+		t.Helper()
+		var jobs kbatch.JobList
+		if err := c.List(ctx, &jobs, client.InNamespace(key.Namespace)); err != nil {
+			t.Fatal(err)
+		}
+		for _, job := range jobs.Items {
+			if job.Labels[batchv1.MatrixEntryLabel] == entry && job.Annotations[batchv1.ScheduledTimeAnnotation] == scheduledTime.Format(time.RFC3339) {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Run("doesn't rerun a Job removed by the history limit", func(t *testing.T) {
+		limit := int32(0)
+		cronJob := newCronJob(func(spec *batchv1.CronJobSpec) { spec.FailedJobsHistoryLimit = &limit })
+		r, c := newTestCronJobReconciler(t, scheme, at(12, 30), cronJob,
+			newTestJob(t, scheme, cronJob, at(12, 0), "eu", kbatch.JobFailed),
+			newTestJob(t, scheme, cronJob, at(12, 0), "us", kbatch.JobComplete))
+
+		reconcile(r, at(12, 30))
+		if hasJob(c, at(12, 0), "eu") {
+			t.Fatal("the failed Job of eu wasn't cleaned up")
+		}
+		reconcile(r, at(12, 31))
+		if hasJob(c, at(12, 0), "eu") {
+			t.Error("eu was run again for 12:00 after its Job was cleaned up")
+		}
+	})
+
+	t.Run("doesn't run an entry late that the concurrency policy skipped", func(t *testing.T) {
+		cronJob := newCronJob(func(spec *batchv1.CronJobSpec) { spec.ConcurrencyPolicy = batchv1.ForbidConcurrent })
+		running := newTestJob(t, scheme, cronJob, at(11, 0), "us", "")
+		r, c := newTestCronJobReconciler(t, scheme, at(12, 0), cronJob,
+			newTestJob(t, scheme, cronJob, at(11, 0), "eu", kbatch.JobComplete), running)
+
+		reconcile(r, at(12, 0))
+		if !hasJob(c, at(12, 0), "eu") {
+			t.Fatal("eu wasn't run for 12:00")
+		}
+		if hasJob(c, at(12, 0), "us") {
+			t.Fatal("us was run for 12:00 while its previous run was still going")
+		}
+
+		running.Status.Conditions = []kbatch.JobCondition{{Type: kbatch.JobComplete, Status: corev1.ConditionTrue}}
+		if err := c.Status().Update(ctx, running); err != nil {
+			t.Fatal(err)
+		}
+		reconcile(r, at(12, 20))
+		if hasJob(c, at(12, 0), "us") {
+			t.Error("us was run late for 12:00 after its previous run finished")
+		}
+		var got batchv1.CronJob
+		if err := c.Get(ctx, key, &got); err != nil {
+			t.Fatal(err)
+		}
+		for _, entryStatus := range got.Status.Matrix {
+			if entryStatus.LastHandledTime == nil || !entryStatus.LastHandledTime.Time.Equal(at(12, 0)) {
+				t.Errorf("entry %s last handled at %v, want 12:00", entryStatus.Name, entryStatus.LastHandledTime)
+			}
+		}
+	})
+}
+
+func TestReconcileCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	at := func(hour, minute int) time.Time { return time.Date(2023, 1, 1, hour, minute, 0, 0, time.UTC) }
+	key := types.NamespacedName{Namespace: "default", Name: "backup"}
+	newCronJob := func(suspend bool) *batchv1.CronJob {
+		return &batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name, UID: "uid", CreationTimestamp: metav1.Time{Time: at(9, 30)}},
+			Spec: batchv1.CronJobSpec{
+				Schedule:          "0 * * * *",
+				ConcurrencyPolicy: batchv1.ForbidConcurrent,
+				Suspend:           &suspend,
+				FailurePolicy:     &batchv1.FailurePolicy{MaxConsecutiveFailures: 2, CoolDown: &metav1.Duration{Duration: time.Hour}},
+				JobTemplate: kbatch.JobTemplateSpec{Spec: kbatch.JobSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "backup", Image: "backup"}},
+				}}}},
+			},
+		}
+	}
+	newFailingCronJob := func(suspend bool) (*CronJobReconciler, client.Client) {
+		cronJob := newCronJob(suspend)
+		return newTestCronJobReconciler(t, scheme, at(11, 30), cronJob,
+			newTestJob(t, scheme, cronJob, at(10, 0), "", kbatch.JobFailed),
+			newTestJob(t, scheme, cronJob, at(11, 0), "", kbatch.JobFailed))
+	}
+	reconcile := func(r *CronJobReconciler, now time.Time) ctrl.Result {
+		t.Helper()
+		r.Clock = &fakeClock{now: now}
+		result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		if err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+		return result
+	}
+	get := func(c client.Client) *batchv1.CronJob {
+		t.Helper()
+		var cronJob batchv1.CronJob
+		if err := c.Get(ctx, key, &cronJob); err != nil {
+			t.Fatal(err)
+		}
+		return &cronJob
+	}
+	state := func(cronJob *batchv1.CronJob) (suspended, open bool) {
+		return cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend,
+			meta.IsStatusConditionTrue(cronJob.Status.Conditions, batchv1.CircuitOpenCondition)
+	}
+
+	t.Run("resumes its own suspension after the cool-down", func(t *testing.T) {
+		r, c := newFailingCronJob(false)
+
+		result := reconcile(r, at(11, 30))
+		got := get(c)
+		if suspended, open := state(got); !suspended || !open || !got.Status.SuspendedByBreaker {
+			t.Fatalf("after 2 failures suspended = %t, circuit open = %t, by the breaker = %t, want all true",
+				suspended, open, got.Status.SuspendedByBreaker)
+		}
+		if result.RequeueAfter != time.Hour {
+			t.Errorf("requeued after %s, want the cool-down of 1h", result.RequeueAfter)
+		}
+
+		result = reconcile(r, at(12, 0))
+		if suspended, open := state(get(c)); !suspended || !open {
+			t.Fatalf("during the cool-down suspended = %t, circuit open = %t, want both true", suspended, open)
+		}
+		if result.RequeueAfter != 30*time.Minute {
+			t.Errorf("requeued after %s, want the rest of the cool-down of 30m", result.RequeueAfter)
+		}
+
+		reconcile(r, at(12, 31))
+		got = get(c)
+		if suspended, open := state(got); suspended || open || got.Status.SuspendedByBreaker {
+			t.Fatalf("after the cool-down suspended = %t, circuit open = %t, by the breaker = %t, want all false",
+				suspended, open, got.Status.SuspendedByBreaker)
+		}
+		if circuit := meta.FindStatusCondition(got.Status.Conditions, batchv1.CircuitOpenCondition); circuit.Reason != "CoolDownPassed" {
+			t.Errorf("circuit closed as %q, want CoolDownPassed", circuit.Reason)
+		}
+		if got.Status.ConsecutiveFailures != 0 {
+			t.Errorf("%d failures in a row after the cool-down, want a fresh start", got.Status.ConsecutiveFailures)
+		}
+	})
+
+	t.Run("leaves a manual suspension alone", func(t *testing.T) {
+		r, c := newFailingCronJob(true)
+
+		reconcile(r, at(11, 30))
+		got := get(c)
+		if suspended, open := state(got); !suspended || !open || got.Status.SuspendedByBreaker {
+			t.Fatalf("after 2 failures suspended = %t, circuit open = %t, by the breaker = %t, want open and suspended by someone else",
+				suspended, open, got.Status.SuspendedByBreaker)
+		}
+
+		reconcile(r, at(13, 0))
+		got = get(c)
+		if suspended, open := state(got); !suspended || !open {
+			t.Fatalf("after the cool-down suspended = %t, circuit open = %t, want the CronJob to stay suspended", suspended, open)
+		}
+
+		suspend := false
+		got.Spec.Suspend = &suspend
+		if err := c.Update(ctx, got); err != nil {
+			t.Fatal(err)
+		}
+		reconcile(r, at(13, 5))
+		got = get(c)
+		if circuit := meta.FindStatusCondition(got.Status.Conditions, batchv1.CircuitOpenCondition); circuit.Status != metav1.ConditionFalse || circuit.Reason != "Resumed" {
+			t.Errorf("circuit %s as %q after resuming by hand, want it closed as Resumed", circuit.Status, circuit.Reason)
+		}
+	})
+}
@@ -0,0 +1,39 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// The metrics are served by the manager, next to those of controller-runtime.
+var (
+	overdueRuns = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cronjob_overdue_runs",
+		Help: "Number of runs of a CronJob that are still going after its expected or maximum duration",
+	}, []string{"namespace", "cronjob", "sla"})
+
+	overdueRunsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cronjob_overdue_runs_total",
+		Help: "Number of runs of a CronJob that went over its expected or maximum duration",
+	}, []string{"namespace", "cronjob", "sla"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(overdueRuns, overdueRunsTotal)
+}
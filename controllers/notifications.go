@@ -0,0 +1,197 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	batchv1 "tutorial.kubebuilder.io/project/api/v1"
+	"tutorial.kubebuilder.io/project/notify"
+)
+
+// notificationTimeout bounds the time spent on delivering a single notification.
+const notificationTimeout = 10 * time.Second
+
+// pendingNotification is a notification on its way to one of the sinks of a CronJob.
+type pendingNotification struct {
+	sink         batchv1.Notification
+	notification notify.Notification
+}
+
+// runEvents returns the events a finished run makes for, most specific first, given the
+// result of the run before it.
+func runEvents(run scheduledRun, lastResult string) []batchv1.NotificationEvent {
+	if run.finishedType == kbatch.JobComplete {
+		if lastResult == "Failed" {
+			return []batchv1.NotificationEvent{batchv1.RecoveryEvent}
+		}
+		return nil
+	}
+	var events []batchv1.NotificationEvent
+	if run.retriesExhausted {
+		events = append(events, batchv1.RetryExhaustedEvent)
+	}
+	if lastResult != "Failed" {
+		events = append(events, batchv1.FirstFailureEvent)
+	}
+	return append(events, batchv1.FailureEvent)
+}
+
+/*
+runNotifications works out the notifications about the runs that were just counted, in
+the order they were scheduled. Each sink hears about a run once at most, as the most
+specific event it asked for. Along the way we keep track of the result of the latest run
+in status.LastResult, which tells a first failure from any other, and a success from a
+recovery.
+*/
+func runNotifications(cronJob *batchv1.CronJob, counted []scheduledRun, now time.Time) []pendingNotification {
+	var pending []pendingNotification
+	for _, run := range counted {
+		events := runEvents(run, cronJob.Status.LastResult)
+		cronJob.Status.LastResult = "Succeeded"
+		if run.finishedType == kbatch.JobFailed {
+			cronJob.Status.LastResult = "Failed"
+		}
+
+		for _, sink := range cronJob.Spec.Notifications {
+			if event, ok := firstSubscribed(sink.Events, events); ok {
+				pending = append(pending, pendingNotification{sink: sink, notification: newNotification(cronJob, event, run, now)})
+			}
+		}
+	}
+	return pending
+}
+
+// eventNotifications returns the notifications about a single event, for the sinks that
+// asked for it.
+func eventNotifications(cronJob *batchv1.CronJob, event batchv1.NotificationEvent, run scheduledRun, now time.Time) []pendingNotification {
+	var pending []pendingNotification
+	for _, sink := range cronJob.Spec.Notifications {
+		if _, ok := firstSubscribed(sink.Events, []batchv1.NotificationEvent{event}); ok {
+			pending = append(pending, pendingNotification{sink: sink, notification: newNotification(cronJob, event, run, now)})
+		}
+	}
+	return pending
+}
+
+// firstSubscribed returns the first of the events that is among the subscribed ones.
+func firstSubscribed(subscribed, events []batchv1.NotificationEvent) (batchv1.NotificationEvent, bool) {
+	for _, event := range events {
+		for _, s := range subscribed {
+			if s == event {
+				return event, true
+			}
+		}
+	}
+	return "", false
+}
+
+func newNotification(cronJob *batchv1.CronJob, event batchv1.NotificationEvent, run scheduledRun, now time.Time) notify.Notification {
+	name := cronJob.Namespace + "/" + cronJob.Name
+	at := run.scheduledTime.Format(time.RFC3339)
+	var message string
+	switch event {
+	case batchv1.FailureEvent:
+		message = fmt.Sprintf("The run of CronJob %s at %s failed", name, at)
+	case batchv1.FirstFailureEvent:
+		message = fmt.Sprintf("CronJob %s started failing: the run at %s failed", name, at)
+	case batchv1.RetryExhaustedEvent:
+		message = fmt.Sprintf("The run of CronJob %s at %s failed after exhausting its retries", name, at)
+	case batchv1.RecoveryEvent:
+		message = fmt.Sprintf("CronJob %s recovered: the run at %s succeeded", name, at)
+	case batchv1.MissedDeadlineEvent:
+		message = fmt.Sprintf("CronJob %s missed the starting deadline of the run at %s", name, at)
+	case batchv1.StaleEvent:
+		message = fmt.Sprintf("CronJob %s went stale at %s: no run succeeded within %s", name, at, cronJob.Spec.SuccessDeadline.Duration)
+	}
+	if run.failedJob != "" {
+		message += fmt.Sprintf(" (Job %s)", run.failedJob)
+	}
+
+	return notify.Notification{
+		// the same for the same event about the same run, so that receivers can drop duplicates
+		ID:            fmt.Sprintf("%s-%d-%s", cronJob.UID, run.scheduledTime.Unix(), strings.ToLower(string(event))),
+		Event:         event,
+		CronJob:       types.NamespacedName{Namespace: cronJob.Namespace, Name: cronJob.Name},
+		ScheduledTime: run.scheduledTime,
+		Job:           run.failedJob,
+		Message:       message,
+		Time:          now,
+	}
+}
+
+// notify delivers the notifications. It's a best effort: a notification that can't be
+// delivered is reported, but never retried, so that nobody hears about anything twice.
+func (r *CronJobReconciler) notify(ctx context.Context, cronJob *batchv1.CronJob, pending []pendingNotification) {
+	log := log.FromContext(ctx)
+	for _, p := range pending {
+		if err := r.sendNotification(ctx, cronJob.Namespace, p); err != nil {
+			log.Error(err, "unable to send notification", "notification", p.sink.Name, "event", p.notification.Event)
+			r.Recorder.Eventf(cronJob, corev1.EventTypeWarning, "FailedNotification", "Unable to send %s notification %s: %v",
+				p.notification.Event, p.sink.Name, err)
+			continue
+		}
+		log.V(1).Info("sent notification", "notification", p.sink.Name, "event", p.notification.Event)
+	}
+}
+
+func (r *CronJobReconciler) sendNotification(ctx context.Context, namespace string, p pendingNotification) error {
+	notifier, err := r.notifierFor(ctx, namespace, &p.sink)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, notificationTimeout)
+	defer cancel()
+	return notifier.Notify(ctx, p.notification)
+}
+
+// notifierFor builds the Notifier for the sink of the notification, with the secrets it needs.
+func (r *CronJobReconciler) notifierFor(ctx context.Context, namespace string, sink *batchv1.Notification) (notify.Notifier, error) {
+	switch {
+	case sink.Webhook != nil:
+		headers, err := r.secretHeaders(ctx, namespace, sink.Webhook.HeadersSecretRef)
+		if err != nil {
+			return nil, err
+		}
+		return &notify.Webhook{URL: sink.Webhook.URL, Headers: headers}, nil
+	case sink.CloudEvents != nil:
+		headers, err := r.secretHeaders(ctx, namespace, sink.CloudEvents.HeadersSecretRef)
+		if err != nil {
+			return nil, err
+		}
+		return &notify.CloudEvents{URL: sink.CloudEvents.URL, Headers: headers}, nil
+	case sink.Email != nil:
+		email := &notify.Email{Server: sink.Email.SMTPServer, From: sink.Email.From, To: sink.Email.To}
+		if ref := sink.Email.CredentialsSecretRef; ref != nil {
+			secret, err := r.getSecret(ctx, namespace, ref)
+			if err != nil {
+				return nil, err
+			}
+			email.Username, email.Password = string(secret.Data["username"]), string(secret.Data["password"])
+		}
+		return email, nil
+	}
+	return nil, fmt.Errorf("notification %s has no sink", sink.Name)
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	kbatch "k8s.io/api/batch/v1"
+
+	batchv1 "tutorial.kubebuilder.io/project/api/v1"
+)
+
+func TestRunNotifications(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	run := func(hour int, finishedType kbatch.JobConditionType, retriesExhausted bool) scheduledRun {
+		return scheduledRun{scheduledTime: start.Add(time.Duration(hour) * time.Hour), finishedType: finishedType, retriesExhausted: retriesExhausted}
+	}
+	cronJob := &batchv1.CronJob{Spec: batchv1.CronJobSpec{Notifications: []batchv1.Notification{
+		{Name: "all", Events: []batchv1.NotificationEvent{batchv1.FailureEvent, batchv1.RecoveryEvent}},
+		{Name: "pager", Events: []batchv1.NotificationEvent{batchv1.FirstFailureEvent, batchv1.RetryExhaustedEvent}},
+	}}}
+
+	pending := runNotifications(cronJob, []scheduledRun{
+		run(0, kbatch.JobComplete, false),
+		run(1, kbatch.JobFailed, false),
+		run(2, kbatch.JobFailed, false),
+		run(3, kbatch.JobFailed, true),
+		run(4, kbatch.JobComplete, false),
+	}, start)
+
+	var got []string
+	for _, p := range pending {
+		got = append(got, p.sink.Name+":"+string(p.notification.Event)+"@"+p.notification.ScheduledTime.Format("15"))
+	}
+	want := []string{
+		"all:Failure@01", "pager:FirstFailure@01",
+		"all:Failure@02",
+		"all:Failure@03", "pager:RetryExhausted@03",
+		"all:Recovery@04",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got notifications %v, want %v", got, want)
+	}
+	if cronJob.Status.LastResult != "Succeeded" {
+		t.Errorf("last result %q, want Succeeded", cronJob.Status.LastResult)
+	}
+}
@@ -0,0 +1,262 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ref "k8s.io/client-go/tools/reference"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	batchv1 "tutorial.kubebuilder.io/project/api/v1"
+)
+
+const scheduledJobOwnerKey = ".metadata.controller.scheduledjob"
+
+// ScheduledJobReconciler reconciles a ScheduledJob object
+type ScheduledJobReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Clock
+	Recorder record.EventRecorder
+
+	// APIReader reads the Job of a ScheduledJob that isn't in the cache, to tell a Job the
+	// cache hasn't seen yet from one that is gone. Defaults to the client.
+	APIReader client.Reader
+
+	// WatchNamespaces restricts the reconciler to ScheduledJobs and Jobs in these
+	// namespaces. All namespaces are reconciled if empty.
+	WatchNamespaces []string
+
+	// Shards, when set, restricts the reconciler to the ScheduledJobs of the shards
+	// this replica owns. They are shared with the CronJobReconciler.
+	Shards *ShardManager
+}
+
+//+kubebuilder:rbac:groups=batch.tutorial.kubebuilder.io,resources=scheduledjobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=batch.tutorial.kubebuilder.io,resources=scheduledjobs/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=batch.tutorial.kubebuilder.io,resources=scheduledjobs/finalizers,verbs=update
+
+/*
+Reconcile works like the one of the CronJob, only there is a single run to take care of:
+we figure out where the ScheduledJob is from its Job, create the Job when its time has come,
+and clean up according to the retention policy once it's all over. A ScheduledJob that
+once had a Job, or was missed, never gets another one.
+*/
+func (r *ScheduledJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	// In sharding mode another replica may own this ScheduledJob. It will be requeued
+	// when we acquire its shard. Otherwise we hold on to the shard until we are done.
+	if r.Shards != nil {
+		done, owned := r.Shards.Track(req.NamespacedName)
+		if !owned {
+			log.V(1).Info("scheduledjob belongs to a shard of another replica, skipping")
+			return ctrl.Result{}, nil
+		}
+		defer done()
+	}
+
+	var scheduledJob batchv1.ScheduledJob
+	if err := r.Get(ctx, req.NamespacedName, &scheduledJob); err != nil {
+		log.Error(err, "unable to fetch ScheduledJob")
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	var childJobs kbatch.JobList
+	if err := r.List(ctx, &childJobs, client.InNamespace(req.Namespace), client.MatchingFields{scheduledJobOwnerKey: req.Name}); err != nil {
+		log.Error(err, "unable to list child Jobs")
+		return ctrl.Result{}, err
+	}
+
+	// Work out the status from the Job, if there is one. Once the Job is gone, e.g. because
+	// its TTL passed, we stick with what we saw last. A Job that is gone before we saw it
+	// finish is lost: it ran, or may have, so it doesn't run again.
+	status := &scheduledJob.Status
+	var job *kbatch.Job
+	if len(childJobs.Items) > 0 {
+		job = &childJobs.Items[0]
+	} else if status.Job != nil && !status.Phase.IsFinished() {
+		// the cache may just not have seen the Job yet, which only the API server can tell
+		var recorded kbatch.Job
+		err := r.apiReader().Get(ctx, types.NamespacedName{Namespace: status.Job.Namespace, Name: status.Job.Name}, &recorded)
+		switch {
+		case err == nil:
+			job = &recorded
+		case apierrors.IsNotFound(err):
+			status.Phase = batchv1.ScheduledJobLost
+			status.CompletionTime = &metav1.Time{Time: r.Now()}
+			r.Recorder.Eventf(&scheduledJob, corev1.EventTypeWarning, "JobLost", "The Job %s is gone before it was seen to finish", status.Job.Name)
+		default:
+			log.Error(err, "unable to fetch Job", "job", status.Job.Name)
+			return ctrl.Result{}, err
+		}
+	}
+	if job != nil {
+		jobRef, err := ref.GetReference(r.Scheme, job)
+		if err != nil {
+			log.Error(err, "unable to make reference to job", "job", job)
+			return ctrl.Result{}, err
+		}
+		status.Job = jobRef
+
+		switch _, finishedType := isJobFinished(job); finishedType {
+		case kbatch.JobComplete:
+			status.Phase = batchv1.ScheduledJobSucceeded
+		case kbatch.JobFailed:
+			status.Phase = batchv1.ScheduledJobFailed
+		default:
+			status.Phase = batchv1.ScheduledJobRunning
+		}
+		if status.Phase.IsFinished() && status.CompletionTime == nil {
+			status.CompletionTime = job.Status.CompletionTime
+			if status.CompletionTime == nil {
+				status.CompletionTime = &metav1.Time{Time: r.Now()}
+			}
+		}
+	} else if status.Phase == "" {
+		status.Phase = batchv1.ScheduledJobPending
+	}
+
+	// Nothing has run yet, but it's too late for it now.
+	runAt := scheduledJob.Spec.RunAt.Time
+	if status.Phase == batchv1.ScheduledJobPending && status.Job == nil && scheduledJob.Spec.StartingDeadlineSeconds != nil &&
+		runAt.Add(time.Duration(*scheduledJob.Spec.StartingDeadlineSeconds)*time.Second).Before(r.Now()) {
+		status.Phase = batchv1.ScheduledJobMissed
+		status.CompletionTime = &metav1.Time{Time: r.Now()}
+		r.Recorder.Eventf(&scheduledJob, corev1.EventTypeWarning, "Missed", "Missed the starting deadline for the run at %s",
+			runAt.Format(time.RFC3339))
+	}
+
+	if err := r.Status().Update(ctx, &scheduledJob); err != nil {
+		log.Error(err, "unable to update ScheduledJob status")
+		return ctrl.Result{}, err
+	}
+
+	// It's all over: clean up, if we're supposed to.
+	if status.Phase.IsFinished() {
+		if scheduledJob.Spec.RetentionPolicy != batchv1.DeleteScheduledJob {
+			return ctrl.Result{}, nil
+		}
+		var ttl time.Duration
+		if scheduledJob.Spec.TTLSecondsAfterFinished != nil {
+			ttl = time.Duration(*scheduledJob.Spec.TTLSecondsAfterFinished) * time.Second
+		}
+		if remaining := status.CompletionTime.Add(ttl).Sub(r.Now()); remaining > 0 {
+			return ctrl.Result{RequeueAfter: remaining}, nil
+		}
+		if err := r.Delete(ctx, &scheduledJob, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+			log.Error(err, "unable to delete finished ScheduledJob")
+			return ctrl.Result{}, err
+		}
+		log.V(0).Info("deleted finished scheduledjob")
+		return ctrl.Result{}, nil
+	}
+
+	// The Job is there already, it just has to finish.
+	if status.Job != nil {
+		return ctrl.Result{}, nil
+	}
+
+	if runAt.After(r.Now()) {
+		log.V(1).Info("not yet time to run, sleeping", "run at", runAt)
+		return ctrl.Result{RequeueAfter: runAt.Sub(r.Now())}, nil
+	}
+
+	job, err := batchv1.ConstructJobForScheduledJob(&scheduledJob, r.Scheme)
+	if err != nil {
+		log.Error(err, "unable to construct job from template")
+		return ctrl.Result{}, r.failCreate(ctx, &scheduledJob, err)
+	}
+	if err := r.Create(ctx, job); err != nil && !apierrors.IsAlreadyExists(err) {
+		log.Error(err, "unable to create Job for ScheduledJob", "job", job)
+		// a Job the API server rejects won't get any better by trying again
+		if apierrors.IsInvalid(err) {
+			return ctrl.Result{}, r.failCreate(ctx, &scheduledJob, err)
+		}
+		return ctrl.Result{}, err
+	}
+	log.V(1).Info("created Job for ScheduledJob run", "job", job)
+	r.Recorder.Eventf(&scheduledJob, corev1.EventTypeNormal, "SuccessfulCreate", "Created job %s", job.Name)
+
+	// Record the Job right away rather than wait for the cache: should the Job be gone before
+	// the cache sees it, the status is all that stops us from creating it again.
+	jobRef, err := ref.GetReference(r.Scheme, job)
+	if err != nil {
+		log.Error(err, "unable to make reference to job", "job", job)
+		return ctrl.Result{}, err
+	}
+	status.Job = jobRef
+	status.Phase = batchv1.ScheduledJobRunning
+	if err := r.Status().Update(ctx, &scheduledJob); err != nil {
+		log.Error(err, "unable to update ScheduledJob status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *ScheduledJobReconciler) apiReader() client.Reader {
+	if r.APIReader == nil {
+		return r.Client
+	}
+	return r.APIReader
+}
+
+// failCreate marks a ScheduledJob whose Job can't be created as Failed, which makes it
+// finished like any other failure, retention policy included.
+func (r *ScheduledJobReconciler) failCreate(ctx context.Context, scheduledJob *batchv1.ScheduledJob, err error) error {
+	scheduledJob.Status.Phase = batchv1.ScheduledJobFailed
+	scheduledJob.Status.CompletionTime = &metav1.Time{Time: r.Now()}
+	if err := r.Status().Update(ctx, scheduledJob); err != nil {
+		log.FromContext(ctx).Error(err, "unable to update ScheduledJob status")
+		return err
+	}
+	r.Recorder.Eventf(scheduledJob, corev1.EventTypeWarning, "FailedCreate", "Unable to create the Job: %v", err)
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ScheduledJobReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// set up a real Clock, since we are not in a test
+	if r.Clock == nil {
+		r.Clock = realClock{}
+	}
+
+	if err := indexJobsByOwner(mgr, scheduledJobOwnerKey, "ScheduledJob"); err != nil {
+		return err
+	}
+
+	bldr := ctrl.NewControllerManagedBy(mgr).
+		For(&batchv1.ScheduledJob{}).
+		Owns(&kbatch.Job{}).
+		WithEventFilter(inNamespaces(r.WatchNamespaces))
+	if r.Shards != nil {
+		bldr = bldr.Watches(r.Shards.ScheduledJobSource(), &handler.EnqueueRequestForObject{})
+	}
+	return bldr.Complete(r)
+}
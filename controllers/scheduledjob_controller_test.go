@@ -0,0 +1,269 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	batchv1 "tutorial.kubebuilder.io/project/api/v1"
+)
+
+// invalidJobClient rejects every Job, like the API server does with a Job it can't accept.
+type invalidJobClient struct {
+	client.Client
+}
+
+func (c invalidJobClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if job, ok := obj.(*kbatch.Job); ok {
+		return apierrors.NewInvalid(schema.GroupKind{Group: "batch", Kind: "Job"}, job.Name,
+			field.ErrorList{field.Invalid(field.NewPath("metadata", "name"), job.Name, "too long")})
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func TestScheduledJobReconcile(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := batchv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	runAt := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	key := types.NamespacedName{Namespace: "default", Name: "migrate"}
+	newScheduledJob := func(mutate func(*batchv1.ScheduledJobSpec)) *batchv1.ScheduledJob {
+		scheduledJob := &batchv1.ScheduledJob{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name, UID: "uid"},
+			Spec: batchv1.ScheduledJobSpec{
+				RunAt: metav1.Time{Time: runAt},
+				JobTemplate: kbatch.JobTemplateSpec{Spec: kbatch.JobSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "migrate", Image: "migrate"}},
+				}}}},
+			},
+		}
+		if mutate != nil {
+			mutate(&scheduledJob.Spec)
+		}
+		return scheduledJob
+	}
+	newJob := func(scheduledJob *batchv1.ScheduledJob, finishedType kbatch.JobConditionType) *kbatch.Job {
+		job, err := batchv1.ConstructJobForScheduledJob(scheduledJob, scheme)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if finishedType != "" {
+			job.Status.Conditions = []kbatch.JobCondition{{Type: finishedType, Status: corev1.ConditionTrue}}
+		}
+		return job
+	}
+	newReconciler := func(now time.Time, objs ...client.Object) (*ScheduledJobReconciler, client.Client) {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).
+			WithIndex(&kbatch.Job{}, scheduledJobOwnerKey, jobOwnerIndexer("ScheduledJob")).Build()
+		return &ScheduledJobReconciler{
+			Client:   c,
+			Scheme:   scheme,
+			Clock:    &fakeClock{now: now},
+			Recorder: record.NewFakeRecorder(10),
+		}, c
+	}
+	reconcile := func(r *ScheduledJobReconciler) ctrl.Result {
+		t.Helper()
+		result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		if err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+		return result
+	}
+	get := func(c client.Client) *batchv1.ScheduledJob {
+		t.Helper()
+		var scheduledJob batchv1.ScheduledJob
+		if err := c.Get(ctx, key, &scheduledJob); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		return &scheduledJob
+	}
+	jobs := func(c client.Client) []kbatch.Job {
+		t.Helper()
+		var jobs kbatch.JobList
+		if err := c.List(ctx, &jobs, client.InNamespace(key.Namespace)); err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		return jobs.Items
+	}
+
+	t.Run("waits for its time", func(t *testing.T) {
+		r, c := newReconciler(runAt.Add(-time.Hour), newScheduledJob(nil))
+		if result := reconcile(r); result.RequeueAfter != time.Hour {
+			t.Errorf("RequeueAfter = %v, want 1h", result.RequeueAfter)
+		}
+		if got := get(c).Status.Phase; got != batchv1.ScheduledJobPending {
+			t.Errorf("phase = %q, want %q", got, batchv1.ScheduledJobPending)
+		}
+		if got := len(jobs(c)); got != 0 {
+			t.Errorf("%d Jobs created, want none", got)
+		}
+	})
+
+	t.Run("creates the Job once it's time", func(t *testing.T) {
+		r, c := newReconciler(runAt.Add(time.Second), newScheduledJob(nil))
+		reconcile(r)
+		if got := len(jobs(c)); got != 1 {
+			t.Fatalf("%d Jobs created, want 1", got)
+		}
+		reconcile(r)
+		if got := len(jobs(c)); got != 1 {
+			t.Errorf("%d Jobs after the second reconcile, want 1", got)
+		}
+		if got := get(c).Status.Phase; got != batchv1.ScheduledJobRunning {
+			t.Errorf("phase = %q, want %q", got, batchv1.ScheduledJobRunning)
+		}
+	})
+
+	t.Run("records the Job right after creating it", func(t *testing.T) {
+		r, c := newReconciler(runAt.Add(time.Second), newScheduledJob(nil))
+		reconcile(r)
+		status := get(c).Status
+		if status.Phase != batchv1.ScheduledJobRunning || status.Job == nil {
+			t.Fatalf("status = %+v, want Running with a Job", status)
+		}
+		if name := jobs(c)[0].Name; status.Job.Name != name {
+			t.Errorf("status.job = %q, want %q", status.Job.Name, name)
+		}
+	})
+
+	t.Run("doesn't run again once the Job is gone", func(t *testing.T) {
+		r, c := newReconciler(runAt.Add(time.Second), newScheduledJob(nil))
+		reconcile(r)
+		job := jobs(c)[0]
+		if err := c.Delete(ctx, &job); err != nil {
+			t.Fatal(err)
+		}
+		reconcile(r)
+		reconcile(r)
+		if got := len(jobs(c)); got != 0 {
+			t.Errorf("%d Jobs after the Job was deleted, want 0", got)
+		}
+		status := get(c).Status
+		if status.Phase != batchv1.ScheduledJobLost || status.CompletionTime == nil {
+			t.Errorf("status = %+v, want Lost with a completion time", status)
+		}
+	})
+
+	t.Run("waits for the cache to see the Job", func(t *testing.T) {
+		r, c := newReconciler(runAt.Add(time.Second), newScheduledJob(nil))
+		reconcile(r)
+		// the cache has the status but not the Job yet
+		r, cache := newReconciler(runAt.Add(time.Second), get(c))
+		r.APIReader = c
+		reconcile(r)
+		if got := len(jobs(cache)); got != 0 {
+			t.Errorf("%d Jobs created again, want 0", got)
+		}
+		if got := get(cache).Status.Phase; got != batchv1.ScheduledJobRunning {
+			t.Errorf("phase = %q, want %q", got, batchv1.ScheduledJobRunning)
+		}
+	})
+
+	t.Run("follows the Job to its end", func(t *testing.T) {
+		scheduledJob := newScheduledJob(nil)
+		r, c := newReconciler(runAt.Add(time.Hour), scheduledJob, newJob(scheduledJob, kbatch.JobComplete))
+		reconcile(r)
+		status := get(c).Status
+		if status.Phase != batchv1.ScheduledJobSucceeded || status.Job == nil || status.CompletionTime == nil {
+			t.Errorf("status = %+v, want Succeeded with a Job and a completion time", status)
+		}
+	})
+
+	t.Run("misses the starting deadline", func(t *testing.T) {
+		deadline := int64(60)
+		r, c := newReconciler(runAt.Add(2*time.Minute), newScheduledJob(func(spec *batchv1.ScheduledJobSpec) {
+			spec.StartingDeadlineSeconds = &deadline
+		}))
+		reconcile(r)
+		if got := get(c).Status.Phase; got != batchv1.ScheduledJobMissed {
+			t.Errorf("phase = %q, want %q", got, batchv1.ScheduledJobMissed)
+		}
+		if got := len(jobs(c)); got != 0 {
+			t.Errorf("%d Jobs created, want none", got)
+		}
+	})
+
+	t.Run("fails when the Job is invalid", func(t *testing.T) {
+		r, c := newReconciler(runAt.Add(time.Second), newScheduledJob(nil))
+		r.Client = invalidJobClient{c}
+		reconcile(r)
+		if got := get(c).Status.Phase; got != batchv1.ScheduledJobFailed {
+			t.Errorf("phase = %q, want %q", got, batchv1.ScheduledJobFailed)
+		}
+		// finished, so there's no retrying
+		reconcile(r)
+		if got := len(jobs(c)); got != 0 {
+			t.Errorf("%d Jobs created, want none", got)
+		}
+	})
+
+	t.Run("deletes itself after the TTL", func(t *testing.T) {
+		ttl := int32(600)
+		scheduledJob := newScheduledJob(func(spec *batchv1.ScheduledJobSpec) {
+			spec.RetentionPolicy = batchv1.DeleteScheduledJob
+			spec.TTLSecondsAfterFinished = &ttl
+		})
+		job := newJob(scheduledJob, kbatch.JobComplete)
+		job.Status.CompletionTime = &metav1.Time{Time: runAt.Add(time.Minute)}
+		r, c := newReconciler(runAt.Add(5*time.Minute), scheduledJob, job)
+		if result := reconcile(r); result.RequeueAfter != 6*time.Minute {
+			t.Errorf("RequeueAfter = %v, want 6m", result.RequeueAfter)
+		}
+		get(c)
+
+		r.Clock = &fakeClock{now: runAt.Add(11 * time.Minute)}
+		reconcile(r)
+		if err := c.Get(ctx, key, &batchv1.ScheduledJob{}); !apierrors.IsNotFound(err) {
+			t.Errorf("Get() error = %v, want NotFound", err)
+		}
+	})
+
+	t.Run("leaves other shards alone", func(t *testing.T) {
+		r, c := newReconciler(runAt.Add(time.Second), newScheduledJob(nil))
+		r.Shards = NewShardManager(nil, c, "replica", 4)
+		reconcile(r)
+		if got := get(c).Status.Phase; got != "" {
+			t.Errorf("phase = %q, want it untouched", got)
+		}
+		if got := len(jobs(c)); got != 0 {
+			t.Errorf("%d Jobs created, want none", got)
+		}
+	})
+}
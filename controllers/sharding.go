@@ -0,0 +1,545 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	coordinationclient "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	batchv1 "tutorial.kubebuilder.io/project/api/v1"
+)
+
+/*
+With leader election, only one replica of the manager does any work. In sharding mode
+every replica is active instead, and the CronJobs and ScheduledJobs are split between
+them: each of them belongs to one of a fixed number of shards, picked by a stable hash of
+its namespace and name, and each shard is owned by whichever replica holds the Lease for it.
+
+Replicas also keep a member Lease of their own, so that they can count each other and
+take no more than their fair share of the shards. When a replica joins, the others drop
+their surplus shards and the newcomer picks them up; when one goes away, its Leases
+expire and the survivors take over.
+
+A replica stops working on a shard well before its Lease could be taken over, and the
+Jobs we create have deterministic names, so even if two replicas briefly disagree about
+a shard, the second Create for the same tick fails with AlreadyExists. When a replica gives
+up a shard, it starts no new reconciles of it right away, but keeps the Lease until those
+that are still running are done.
+*/
+
+const (
+	// drainPollInterval is how often releaseAll checks whether a shard has drained
+	drainPollInterval = 50 * time.Millisecond
+
+	shardLeasePrefix  = "cronjob-shard-"
+	memberLeasePrefix = "cronjob-shard-member-"
+	shardLeaseLabel   = "batch.tutorial.kubebuilder.io/shard-lease"
+)
+
+// ShardFor returns the shard a CronJob or ScheduledJob belongs to. It only depends on the
+// namespace and name, so every replica comes to the same answer.
+func ShardFor(key types.NamespacedName, shards int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key.String()))
+	return int(h.Sum32() % uint32(shards))
+}
+
+// ShardManager claims shards of the CronJobs and ScheduledJobs through Leases, and tells
+// the reconcilers which ones are theirs to handle.
+type ShardManager struct {
+	// Leases is where the shard and member Leases live, usually the namespace of the manager
+	Leases coordinationclient.LeaseInterface
+	// Client is used to look up the CronJobs of newly acquired shards
+	Client client.Client
+	Clock
+
+	Identity string
+	Shards   int
+
+	// LeaseDuration is how long other replicas wait before taking over a Lease that
+	// isn't renewed. We stop working on a shard RenewDeadline after the last successful
+	// renewal, and retry every RetryPeriod.
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+
+	mu sync.RWMutex
+	// held maps the shards we own to the time we last renewed their Lease
+	held map[int]time.Time
+	// draining holds the shards we gave up or lost, until their reconciles are done
+	draining map[int]bool
+	// inFlight counts the running reconciles of each shard
+	inFlight           map[int]int
+	events             chan event.GenericEvent
+	scheduledJobEvents chan event.GenericEvent
+}
+
+// NewShardManager returns a ShardManager with the same timings as the manager's leader election.
+func NewShardManager(leases coordinationclient.LeaseInterface, c client.Client, identity string, shards int) *ShardManager {
+	return &ShardManager{
+		Leases:             leases,
+		Client:             c,
+		Clock:              realClock{},
+		Identity:           identity,
+		Shards:             shards,
+		LeaseDuration:      15 * time.Second,
+		RenewDeadline:      10 * time.Second,
+		RetryPeriod:        2 * time.Second,
+		held:               make(map[int]time.Time),
+		draining:           make(map[int]bool),
+		inFlight:           make(map[int]int),
+		events:             make(chan event.GenericEvent),
+		scheduledJobEvents: make(chan event.GenericEvent),
+	}
+}
+
+// Owns reports whether the CronJob or ScheduledJob belongs to a shard we currently own.
+func (m *ShardManager) Owns(key types.NamespacedName) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	renewed, ok := m.held[ShardFor(key, m.Shards)]
+	return ok && m.Now().Before(renewed.Add(m.RenewDeadline))
+}
+
+// Track reports whether the CronJob or ScheduledJob belongs to a shard we currently own,
+// like Owns, and if so counts a reconcile of it as running until done is called. We don't
+// let go of the Lease of a shard while any of its reconciles run.
+func (m *ShardManager) Track(key types.NamespacedName) (done func(), owned bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	shard := ShardFor(key, m.Shards)
+	if renewed, ok := m.held[shard]; !ok || !m.Now().Before(renewed.Add(m.RenewDeadline)) {
+		return nil, false
+	}
+	m.inFlight[shard]++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			if m.inFlight[shard]--; m.inFlight[shard] == 0 {
+				delete(m.inFlight, shard)
+			}
+		})
+	}, true
+}
+
+// Source emits an event for every CronJob of a shard we have just acquired, so that
+// they are reconciled right away rather than on their next change.
+func (m *ShardManager) Source() source.Source {
+	return &source.Channel{Source: m.events}
+}
+
+// ScheduledJobSource is what Source is for CronJobs, for ScheduledJobs.
+func (m *ShardManager) ScheduledJobSource() source.Source {
+	return &source.Channel{Source: m.scheduledJobEvents}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable; every replica claims shards.
+func (m *ShardManager) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable.
+func (m *ShardManager) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithValues("identity", m.Identity, "shards", m.Shards)
+	ctx = log.IntoContext(ctx, logger)
+	logger.Info("starting shard manager")
+
+	ticker := time.NewTicker(m.RetryPeriod)
+	defer ticker.Stop()
+	for {
+		m.sync(ctx)
+		select {
+		case <-ctx.Done():
+			// hand our shards over right away instead of letting the Leases expire
+			m.releaseAll(context.Background())
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync renews our Leases, works out our fair share of the shards and acquires or
+// releases shards to match it.
+func (m *ShardManager) sync(ctx context.Context) {
+	log := log.FromContext(ctx)
+	now := m.Now()
+
+	if err := m.heartbeat(ctx, now); err != nil {
+		log.Error(err, "unable to renew member lease")
+	}
+
+	leases, err := m.Leases.List(ctx, metav1.ListOptions{LabelSelector: shardLeaseLabel})
+	if err != nil {
+		log.Error(err, "unable to list shard leases")
+		return
+	}
+
+	members := 0
+	shardLeases := make(map[string]*coordinationv1.Lease)
+	for i := range leases.Items {
+		lease := &leases.Items[i]
+		switch lease.Labels[shardLeaseLabel] {
+		case "member":
+			if !m.expired(lease, now) {
+				members++
+			}
+		case "shard":
+			shardLeases[lease.Name] = lease
+		}
+	}
+	if members == 0 {
+		// our own heartbeat failed; don't go grabbing everything
+		members = 1
+	}
+	target := (m.Shards + members - 1) / members
+
+	// forget about what we lost..
+	for _, shard := range m.heldShards() {
+		if lease := shardLeases[shardLeaseName(shard)]; lease == nil || !m.holds(lease) {
+			log.Info("lost shard", "shard", shard)
+			m.drop(shard)
+		}
+	}
+
+	// ..give up our surplus, starting with the highest shards..
+	held := m.heldShards()
+	for len(held) > target {
+		shard := held[len(held)-1]
+		held = held[:len(held)-1]
+		log.Info("releasing shard", "shard", shard, "target", target)
+		m.drop(shard)
+	}
+
+	// ..let go of the Leases of the shards we gave up once their reconciles are done, and
+	// keep them until then..
+	for _, shard := range m.drainingShards() {
+		lease := shardLeases[shardLeaseName(shard)]
+		ours := lease != nil && m.holds(lease)
+		if m.busy(shard) {
+			if ours {
+				if err := m.renew(ctx, lease, now); err != nil {
+					log.Error(err, "unable to renew lease of releasing shard", "shard", shard)
+				}
+			}
+			continue
+		}
+		if ours {
+			if err := m.release(ctx, lease); err != nil {
+				log.Error(err, "unable to release shard lease", "shard", shard)
+				continue
+			}
+		}
+		log.Info("released shard", "shard", shard)
+		m.forget(shard)
+		m.clearMetrics(ctx, shard)
+	}
+
+	// ..and renew the rest
+	for _, shard := range held {
+		if err := m.renew(ctx, shardLeases[shardLeaseName(shard)], now); err != nil {
+			log.Error(err, "unable to renew shard lease", "shard", shard)
+		}
+	}
+
+	// and pick up free shards up to our share. We start looking at an offset derived
+	// from our identity so that replicas don't all race for the same shard.
+	offset := ShardFor(types.NamespacedName{Name: m.Identity}, m.Shards)
+	for i := 0; i < m.Shards && len(m.heldShards()) < target; i++ {
+		shard := (offset + i) % m.Shards
+		if _, ok := m.heldAt(shard); ok {
+			continue
+		}
+		lease := shardLeases[shardLeaseName(shard)]
+		if lease != nil && !m.expired(lease, now) {
+			continue
+		}
+		if err := m.acquire(ctx, shard, lease, now); err != nil {
+			// most likely someone else was quicker
+			log.V(1).Info("unable to acquire shard", "shard", shard, "reason", err.Error())
+			continue
+		}
+		log.Info("acquired shard", "shard", shard, "target", target)
+		go m.enqueueShard(ctx, shard)
+	}
+}
+
+// heartbeat creates or renews our member Lease.
+func (m *ShardManager) heartbeat(ctx context.Context, now time.Time) error {
+	name := memberLeasePrefix + m.Identity
+	lease, err := m.Leases.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		lease = m.newLease(name, "member", now)
+		_, err = m.Leases.Create(ctx, lease, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	return m.renew(ctx, lease, now)
+}
+
+func (m *ShardManager) acquire(ctx context.Context, shard int, lease *coordinationv1.Lease, now time.Time) error {
+	if lease == nil {
+		lease = m.newLease(shardLeaseName(shard), "shard", now)
+		if _, err := m.Leases.Create(ctx, lease, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+	} else {
+		lease = lease.DeepCopy()
+		transitions := int32(0)
+		if lease.Spec.LeaseTransitions != nil {
+			transitions = *lease.Spec.LeaseTransitions
+		}
+		transitions++
+		lease.Spec.HolderIdentity = &m.Identity
+		lease.Spec.LeaseDurationSeconds = m.leaseDurationSeconds()
+		lease.Spec.AcquireTime = &metav1.MicroTime{Time: now}
+		lease.Spec.RenewTime = &metav1.MicroTime{Time: now}
+		lease.Spec.LeaseTransitions = &transitions
+		// the update carries the resourceVersion we looked at, so only one replica wins
+		if _, err := m.Leases.Update(ctx, lease, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.held[shard] = now
+	return nil
+}
+
+func (m *ShardManager) renew(ctx context.Context, lease *coordinationv1.Lease, now time.Time) error {
+	lease = lease.DeepCopy()
+	lease.Spec.RenewTime = &metav1.MicroTime{Time: now}
+	lease.Spec.LeaseDurationSeconds = m.leaseDurationSeconds()
+	if _, err := m.Leases.Update(ctx, lease, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+
+	if lease.Labels[shardLeaseLabel] == "shard" {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		// draining shards keep their Lease, but aren't ours to work on anymore
+		if shard, ok := shardFromLeaseName(lease.Name); ok && !m.draining[shard] {
+			m.held[shard] = now
+		}
+	}
+	return nil
+}
+
+// release clears the holder of a shard Lease, so that another replica can pick it up
+// without waiting for it to expire. The shard must have been drained already.
+func (m *ShardManager) release(ctx context.Context, lease *coordinationv1.Lease) error {
+	lease = lease.DeepCopy()
+	lease.Spec.HolderIdentity = nil
+	lease.Spec.AcquireTime = nil
+	lease.Spec.RenewTime = nil
+	_, err := m.Leases.Update(ctx, lease, metav1.UpdateOptions{})
+	return err
+}
+
+// releaseAll hands over all our shards when shutting down, giving the reconciles that
+// are still running up to RenewDeadline to finish first.
+func (m *ShardManager) releaseAll(ctx context.Context) {
+	log := log.FromContext(ctx)
+	for _, shard := range m.heldShards() {
+		m.drop(shard)
+	}
+	deadline := time.Now().Add(m.RenewDeadline)
+	for _, shard := range m.drainingShards() {
+		for m.busy(shard) && time.Now().Before(deadline) {
+			time.Sleep(drainPollInterval)
+		}
+		m.forget(shard)
+		lease, err := m.Leases.Get(ctx, shardLeaseName(shard), metav1.GetOptions{})
+		if err == nil && m.holds(lease) {
+			err = m.release(ctx, lease)
+		}
+		if err != nil {
+			log.Error(err, "unable to release shard lease", "shard", shard)
+		}
+	}
+	if err := m.Leases.Delete(ctx, memberLeasePrefix+m.Identity, metav1.DeleteOptions{}); client.IgnoreNotFound(err) != nil {
+		log.Error(err, "unable to delete member lease")
+	}
+}
+
+// enqueueShard triggers a reconcile of every CronJob and ScheduledJob in the shard.
+func (m *ShardManager) enqueueShard(ctx context.Context, shard int) {
+	var cronJobs batchv1.CronJobList
+	if err := m.Client.List(ctx, &cronJobs); err != nil {
+		log.FromContext(ctx).Error(err, "unable to list CronJobs of acquired shard", "shard", shard)
+	}
+	for i := range cronJobs.Items {
+		if !m.enqueue(ctx, shard, &cronJobs.Items[i], m.events) {
+			return
+		}
+	}
+
+	var scheduledJobs batchv1.ScheduledJobList
+	if err := m.Client.List(ctx, &scheduledJobs); err != nil {
+		log.FromContext(ctx).Error(err, "unable to list ScheduledJobs of acquired shard", "shard", shard)
+	}
+	for i := range scheduledJobs.Items {
+		if !m.enqueue(ctx, shard, &scheduledJobs.Items[i], m.scheduledJobEvents) {
+			return
+		}
+	}
+}
+
+// enqueue sends an event for the object if it is in the shard. It returns false once
+// we're shutting down.
+func (m *ShardManager) enqueue(ctx context.Context, shard int, obj client.Object, events chan<- event.GenericEvent) bool {
+	if ShardFor(client.ObjectKeyFromObject(obj), m.Shards) != shard {
+		return true
+	}
+	select {
+	case events <- event.GenericEvent{Object: obj}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (m *ShardManager) newLease(name, kind string, now time.Time) *coordinationv1.Lease {
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{shardLeaseLabel: kind},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &m.Identity,
+			LeaseDurationSeconds: m.leaseDurationSeconds(),
+			AcquireTime:          &metav1.MicroTime{Time: now},
+			RenewTime:            &metav1.MicroTime{Time: now},
+		},
+	}
+}
+
+func (m *ShardManager) leaseDurationSeconds() *int32 {
+	seconds := int32(m.LeaseDuration / time.Second)
+	return &seconds
+}
+
+func (m *ShardManager) holds(lease *coordinationv1.Lease) bool {
+	return lease.Spec.HolderIdentity != nil && *lease.Spec.HolderIdentity == m.Identity
+}
+
+// expired reports whether a Lease is free for the taking.
+func (m *ShardManager) expired(lease *coordinationv1.Lease, now time.Time) bool {
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity == "" || lease.Spec.RenewTime == nil {
+		return true
+	}
+	duration := m.LeaseDuration
+	if lease.Spec.LeaseDurationSeconds != nil {
+		duration = time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
+	}
+	return lease.Spec.RenewTime.Add(duration).Before(now)
+}
+
+func (m *ShardManager) heldShards() []int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	shards := make([]int, 0, len(m.held))
+	for shard := range m.held {
+		shards = append(shards, shard)
+	}
+	sort.Ints(shards)
+	return shards
+}
+
+func (m *ShardManager) heldAt(shard int) (time.Time, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	renewed, ok := m.held[shard]
+	return renewed, ok
+}
+
+// drop stops us from working on a shard, which drains until forgotten.
+func (m *ShardManager) drop(shard int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.held, shard)
+	m.draining[shard] = true
+}
+
+func (m *ShardManager) forget(shard int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.draining, shard)
+}
+
+func (m *ShardManager) drainingShards() []int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	shards := make([]int, 0, len(m.draining))
+	for shard := range m.draining {
+		shards = append(shards, shard)
+	}
+	sort.Ints(shards)
+	return shards
+}
+
+// busy reports whether any reconciles of the shard are running.
+func (m *ShardManager) busy(shard int) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.inFlight[shard] > 0
+}
+
+// clearMetrics drops the overdue runs of the CronJobs of a shard we gave up, which the
+// replica that takes it over reports from now on.
+func (m *ShardManager) clearMetrics(ctx context.Context, shard int) {
+	var cronJobs batchv1.CronJobList
+	if err := m.Client.List(ctx, &cronJobs); err != nil {
+		log.FromContext(ctx).Error(err, "unable to list CronJobs of released shard", "shard", shard)
+		return
+	}
+	for i := range cronJobs.Items {
+		if key := client.ObjectKeyFromObject(&cronJobs.Items[i]); ShardFor(key, m.Shards) == shard {
+			overdueRuns.DeletePartialMatch(map[string]string{"namespace": key.Namespace, "cronjob": key.Name})
+		}
+	}
+}
+
+func shardLeaseName(shard int) string {
+	return fmt.Sprintf("%s%d", shardLeasePrefix, shard)
+}
+
+func shardFromLeaseName(name string) (int, bool) {
+	var shard int
+	if _, err := fmt.Sscanf(name, shardLeasePrefix+"%d", &shard); err != nil {
+		return 0, false
+	}
+	return shard, true
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	batchv1 "tutorial.kubebuilder.io/project/api/v1"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func TestShardManagerRebalances(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	scheme := runtime.NewScheme()
+	if err := batchv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	leases := kubefake.NewSimpleClientset().CoordinationV1().Leases("project-system")
+	clock := &fakeClock{now: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	const shards = 4
+	var keys []types.NamespacedName
+	for i := 0; i < 20; i++ {
+		keys = append(keys, types.NamespacedName{Namespace: "default", Name: fmt.Sprintf("cronjob-%d", i)})
+	}
+	newReplica := func(identity string) *ShardManager {
+		m := NewShardManager(leases, c, identity, shards)
+		m.Clock = clock
+		return m
+	}
+	// owners returns which replica owns each shard, failing on double ownership
+	owners := func(replicas ...*ShardManager) map[int]string {
+		t.Helper()
+		owned := make(map[int]string)
+		for _, m := range replicas {
+			for _, shard := range m.heldShards() {
+				if other, ok := owned[shard]; ok {
+					t.Fatalf("shard %d owned by both %s and %s", shard, other, m.Identity)
+				}
+				owned[shard] = m.Identity
+			}
+		}
+		return owned
+	}
+
+	a := newReplica("replica-a")
+	a.sync(ctx)
+	if got := len(owners(a)); got != shards {
+		t.Fatalf("single replica owns %d shards, want %d", got, shards)
+	}
+
+	// a second replica joins: a gives up half of the shards, and b picks them up
+	b := newReplica("replica-b")
+	b.sync(ctx)
+	clock.now = clock.now.Add(a.RetryPeriod)
+	a.sync(ctx)
+	b.sync(ctx)
+	owners(a, b)
+	if len(a.heldShards()) != shards/2 || len(b.heldShards()) != shards/2 {
+		t.Fatalf("shards not balanced after b joined: a=%v b=%v", a.heldShards(), b.heldShards())
+	}
+	for _, key := range keys {
+		if a.Owns(key) == b.Owns(key) {
+			t.Fatalf("%s must be owned by exactly one replica", key)
+		}
+	}
+
+	// b goes away without a word: once its Leases expire, a takes everything back
+	clock.now = clock.now.Add(a.LeaseDuration + time.Second)
+	a.sync(ctx)
+	if got := len(a.heldShards()); got != shards {
+		t.Fatalf("surviving replica owns %d shards, want %d", got, shards)
+	}
+	for _, key := range keys {
+		if b.Owns(key) {
+			t.Fatalf("expired replica still claims %s", key)
+		}
+	}
+}
+
+func TestShardManagerHandsOverWhenDrained(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	scheme := runtime.NewScheme()
+	if err := batchv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	const shards = 2
+	// a CronJob in the shard the first replica gives up once the second one joins
+	var key types.NamespacedName
+	for i := 0; ShardFor(key, shards) != shards-1 || key.Name == ""; i++ {
+		key = types.NamespacedName{Namespace: "handover", Name: fmt.Sprintf("cronjob-%d", i)}
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(&batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}).Build()
+	leases := kubefake.NewSimpleClientset().CoordinationV1().Leases("project-system")
+	clock := &fakeClock{now: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+	newReplica := func(identity string) *ShardManager {
+		m := NewShardManager(leases, c, identity, shards)
+		m.Clock = clock
+		return m
+	}
+	holder := func() string {
+		t.Helper()
+		lease, err := leases.Get(ctx, shardLeaseName(shards-1), metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lease.Spec.HolderIdentity == nil {
+			return ""
+		}
+		return *lease.Spec.HolderIdentity
+	}
+
+	a := newReplica("replica-a")
+	a.sync(ctx)
+	done, owned := a.Track(key)
+	if !owned {
+		t.Fatalf("single replica doesn't own %s", key)
+	}
+	overdueRuns.WithLabelValues(key.Namespace, key.Name, "expectedDuration").Set(1)
+	series := testutil.CollectAndCount(overdueRuns)
+
+	// b joins while a is still reconciling the CronJob, past the time the Lease would expire
+	b := newReplica("replica-b")
+	for i := 0; i < 10; i++ {
+		b.sync(ctx)
+		clock.now = clock.now.Add(a.RetryPeriod)
+		a.sync(ctx)
+		if _, owned := a.Track(key); owned {
+			t.Fatalf("a starts reconciles of %s after giving up its shard", key)
+		}
+		if b.Owns(key) || holder() != a.Identity {
+			t.Fatalf("%s handed over to %q while a is still reconciling it", key, holder())
+		}
+	}
+	if got := testutil.CollectAndCount(overdueRuns); got != series {
+		t.Fatalf("%d overdue runs series while draining, want %d", got, series)
+	}
+
+	// once the reconcile is done, a lets go and b takes over
+	done()
+	done()
+	a.sync(ctx)
+	if holder() != "" {
+		t.Errorf("shard lease held by %q after draining, want it released", holder())
+	}
+	if got := testutil.CollectAndCount(overdueRuns); got != series-1 {
+		t.Errorf("%d overdue runs series after the handover, want %d", got, series-1)
+	}
+	b.sync(ctx)
+	if !b.Owns(key) || a.Owns(key) {
+		t.Errorf("%s owned by a = %t, b = %t, want b only", key, a.Owns(key), b.Owns(key))
+	}
+}
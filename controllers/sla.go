@@ -0,0 +1,139 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	batchv1 "tutorial.kubebuilder.io/project/api/v1"
+)
+
+// The SLAs a run can go over, named after their fields.
+const (
+	expectedDurationSLA = "ExpectedDuration"
+	maxDurationSLA      = "MaxDuration"
+)
+
+// overdueSLA tells which SLA of the CronJob a run that started at start has gone over by
+// now, the maximum duration first, and when it goes over the next one, if ever.
+func overdueSLA(cronJob *batchv1.CronJob, start, now time.Time) (sla string, next time.Time) {
+	if d := cronJob.Spec.MaxDuration; d != nil {
+		if end := start.Add(d.Duration); now.Before(end) {
+			next = end
+		} else {
+			return maxDurationSLA, time.Time{}
+		}
+	}
+	if d := cronJob.Spec.ExpectedDuration; d != nil {
+		if end := start.Add(d.Duration); now.Before(end) {
+			next = end
+		} else {
+			return expectedDurationSLA, next
+		}
+	}
+	return "", next
+}
+
+/*
+checkDurations holds the jobs that are still going against the expected and maximum
+duration of the CronJob, and sets the RunOverdue condition accordingly. Each job is
+reported once per SLA it goes over, with an Event and in the metrics; we mark it with an
+annotation to remember that across reconciles. It returns when the next job goes over an
+SLA, so that we can come back right then rather than at the next tick.
+*/
+func (r *CronJobReconciler) checkDurations(ctx context.Context, cronJob *batchv1.CronJob, activeJobs []client.Object) (nextCheck time.Time) {
+	log := log.FromContext(ctx)
+	if cronJob.Spec.ExpectedDuration == nil && cronJob.Spec.MaxDuration == nil {
+		meta.RemoveStatusCondition(&cronJob.Status.Conditions, batchv1.RunOverdueCondition)
+		overdueRuns.DeletePartialMatch(map[string]string{"namespace": cronJob.Namespace, "cronjob": cronJob.Name})
+		return time.Time{}
+	}
+
+	overdue := map[string][]string{}
+	for _, job := range activeJobs {
+		start := jobStartTime(job)
+		if start == nil {
+			// not started yet, so not late either
+			continue
+		}
+		sla, next := overdueSLA(cronJob, start.Time, r.Now())
+		if !next.IsZero() && (nextCheck.IsZero() || next.Before(nextCheck)) {
+			nextCheck = next
+		}
+		if sla == "" {
+			continue
+		}
+		overdue[sla] = append(overdue[sla], job.GetName())
+		if job.GetAnnotations()[batchv1.OverdueAnnotation] == sla {
+			continue
+		}
+
+		patch := client.MergeFrom(job.DeepCopyObject().(client.Object))
+		annotations := job.GetAnnotations()
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[batchv1.OverdueAnnotation] = sla
+		job.SetAnnotations(annotations)
+		if err := r.Patch(ctx, job, patch); err != nil {
+			// we'll report it next time around
+			log.Error(err, "unable to mark overdue job", "job", job.GetName())
+			continue
+		}
+		r.Recorder.Eventf(cronJob, corev1.EventTypeWarning, "RunOverdue", "Job %s is running for longer than its %s of %s",
+			job.GetName(), sla, slaDuration(cronJob, sla))
+		overdueRunsTotal.WithLabelValues(cronJob.Namespace, cronJob.Name, sla).Inc()
+	}
+
+	for _, sla := range []string{expectedDurationSLA, maxDurationSLA} {
+		overdueRuns.WithLabelValues(cronJob.Namespace, cronJob.Name, sla).Set(float64(len(overdue[sla])))
+	}
+
+	condition := metav1.Condition{
+		Type:               batchv1.RunOverdueCondition,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: cronJob.Generation,
+		Reason:             "OnTime",
+		Message:            "No run is taking longer than expected",
+	}
+	for _, sla := range []string{maxDurationSLA, expectedDurationSLA} {
+		if jobs := overdue[sla]; len(jobs) > 0 {
+			condition.Status = metav1.ConditionTrue
+			condition.Reason = sla + "Exceeded"
+			condition.Message = fmt.Sprintf("Running for longer than the %s of %s: %s", sla, slaDuration(cronJob, sla), strings.Join(jobs, ", "))
+			break
+		}
+	}
+	meta.SetStatusCondition(&cronJob.Status.Conditions, condition)
+	return nextCheck
+}
+
+func slaDuration(cronJob *batchv1.CronJob, sla string) time.Duration {
+	if sla == maxDurationSLA {
+		return cronJob.Spec.MaxDuration.Duration
+	}
+	return cronJob.Spec.ExpectedDuration.Duration
+}
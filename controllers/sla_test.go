@@ -0,0 +1,53 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	batchv1 "tutorial.kubebuilder.io/project/api/v1"
+)
+
+func TestOverdueSLA(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	minutes := func(m int) time.Time { return start.Add(time.Duration(m) * time.Minute) }
+	duration := func(m int) *metav1.Duration { return &metav1.Duration{Duration: time.Duration(m) * time.Minute} }
+
+	both := &batchv1.CronJob{Spec: batchv1.CronJobSpec{ExpectedDuration: duration(10), MaxDuration: duration(30)}}
+	expectedOnly := &batchv1.CronJob{Spec: batchv1.CronJobSpec{ExpectedDuration: duration(10)}}
+	for name, tc := range map[string]struct {
+		cronJob  *batchv1.CronJob
+		now      time.Time
+		wantSLA  string
+		wantNext time.Time
+	}{
+		"on time":           {both, minutes(5), "", minutes(10)},
+		"right at expected": {both, minutes(10), expectedDurationSLA, minutes(30)},
+		"over expected":     {both, minutes(20), expectedDurationSLA, minutes(30)},
+		"over max":          {both, minutes(40), maxDurationSLA, time.Time{}},
+		"over the only SLA": {expectedOnly, minutes(20), expectedDurationSLA, time.Time{}},
+		"no SLA":            {&batchv1.CronJob{}, minutes(20), "", time.Time{}},
+	} {
+		sla, next := overdueSLA(tc.cronJob, start, tc.now)
+		if sla != tc.wantSLA || !next.Equal(tc.wantNext) {
+			t.Errorf("%s: overdueSLA() = %q, %s; want %q, %s", name, sla, next, tc.wantSLA, tc.wantNext)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	kbatch "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	batchv1 "tutorial.kubebuilder.io/project/api/v1"
+)
+
+// trackLastSuccess moves status.LastSuccessfulTime up to the latest of the successful jobs
+// and actions. Every successful job counts, whether the runs before it were counted yet
+// or not, so that a run hanging around doesn't hide the ones that went fine after it.
+func trackLastSuccess(status *batchv1.CronJobStatus, successfulJobs []client.Object) {
+	latest := status.LastSuccessfulTime
+	for _, job := range successfulJobs {
+		if finished := jobCompletionTime(job); latest == nil || latest.Before(finished) {
+			latest = finished
+		}
+	}
+	for i := range status.ActionHistory {
+		record := &status.ActionHistory[i]
+		if record.Result == batchv1.ActionSucceeded && record.CompletionTime != nil && (latest == nil || latest.Before(record.CompletionTime)) {
+			latest = record.CompletionTime
+		}
+	}
+	if latest != nil {
+		status.LastSuccessfulTime = &metav1.Time{Time: latest.Time}
+	}
+}
+
+// jobCompletionTime tells when a finished job finished. Other kinds of objects don't say,
+// so we go by when they were created, which errs on the early side.
+func jobCompletionTime(job client.Object) *metav1.Time {
+	if job, ok := job.(*kbatch.Job); ok && job.Status.CompletionTime != nil {
+		return job.Status.CompletionTime
+	}
+	created := job.GetCreationTimestamp()
+	return &created
+}
+
+// staleAt returns when the CronJob goes stale, or went stale, unless it succeeds before:
+// its success deadline after its last success, or after it was created if it never
+// succeeded.
+func staleAt(cronJob *batchv1.CronJob) time.Time {
+	since := cronJob.CreationTimestamp
+	if cronJob.Status.LastSuccessfulTime != nil {
+		since = *cronJob.Status.LastSuccessfulTime
+	}
+	return since.Add(cronJob.Spec.SuccessDeadline.Duration)
+}
+
+/*
+checkStale sets the Stale condition of the CronJob. It only looks at the last success,
+not at why there wasn't any since, so it catches runs that fail, runs that hang, and runs
+that never happen because the schedule is wedged alike. Going stale is reported once,
+with the message of a Warning Event and the notifications it returns, both of which are
+for once the status has been updated. It also returns when the CronJob goes stale, so that
+we can come back right then.
+*/
+func (r *CronJobReconciler) checkStale(cronJob *batchv1.CronJob) (staleMessage string, pending []pendingNotification, nextCheck time.Time) {
+	if cronJob.Spec.SuccessDeadline == nil {
+		meta.RemoveStatusCondition(&cronJob.Status.Conditions, batchv1.StaleCondition)
+		return "", nil, time.Time{}
+	}
+
+	deadline := staleAt(cronJob)
+	if r.Now().Before(deadline) {
+		meta.SetStatusCondition(&cronJob.Status.Conditions, metav1.Condition{
+			Type:               batchv1.StaleCondition,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: cronJob.Generation,
+			Reason:             "RecentSuccess",
+			Message:            fmt.Sprintf("The CronJob has until %s to succeed", deadline.Format(time.RFC3339)),
+		})
+		return "", nil, deadline
+	}
+
+	message := fmt.Sprintf("No run succeeded within %s", cronJob.Spec.SuccessDeadline.Duration)
+	if last := cronJob.Status.LastSuccessfulTime; last != nil {
+		message += fmt.Sprintf(", the last one was at %s", last.Format(time.RFC3339))
+	}
+	if !meta.IsStatusConditionTrue(cronJob.Status.Conditions, batchv1.StaleCondition) {
+		staleMessage = message
+		pending = eventNotifications(cronJob, batchv1.StaleEvent, scheduledRun{scheduledTime: deadline}, r.Now())
+	}
+	meta.SetStatusCondition(&cronJob.Status.Conditions, metav1.Condition{
+		Type:               batchv1.StaleCondition,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: cronJob.Generation,
+		Reason:             "SuccessDeadlineExceeded",
+		Message:            message,
+	})
+	return staleMessage, pending, time.Time{}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	kbatch "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	batchv1 "tutorial.kubebuilder.io/project/api/v1"
+)
+
+func TestTrackLastSuccess(t *testing.T) {
+	at := func(hour int) *metav1.Time {
+		return &metav1.Time{Time: time.Date(2023, 1, 1, hour, 0, 0, 0, time.UTC)}
+	}
+	job := func(hour int) client.Object {
+		return &kbatch.Job{Status: kbatch.JobStatus{CompletionTime: at(hour)}}
+	}
+
+	status := &batchv1.CronJobStatus{LastSuccessfulTime: at(5)}
+	trackLastSuccess(status, []client.Object{job(3), job(7), job(6)})
+	if !status.LastSuccessfulTime.Equal(at(7)) {
+		t.Errorf("LastSuccessfulTime = %s, want %s", status.LastSuccessfulTime, at(7))
+	}
+
+	// the Jobs being gone doesn't make us forget
+	trackLastSuccess(status, nil)
+	if !status.LastSuccessfulTime.Equal(at(7)) {
+		t.Errorf("LastSuccessfulTime = %s after the Jobs were gone, want %s", status.LastSuccessfulTime, at(7))
+	}
+
+	status.ActionHistory = []batchv1.ActionRecord{
+		{CompletionTime: at(8), Result: batchv1.ActionSucceeded},
+		{CompletionTime: at(9), Result: batchv1.ActionFailed},
+	}
+	trackLastSuccess(status, nil)
+	if !status.LastSuccessfulTime.Equal(at(8)) {
+		t.Errorf("LastSuccessfulTime = %s with actions, want %s", status.LastSuccessfulTime, at(8))
+	}
+}
+
+func TestCheckStale(t *testing.T) {
+	created := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	hours := func(h int) time.Time { return created.Add(time.Duration(h) * time.Hour) }
+	clock := &fakeClock{}
+	recorder := record.NewFakeRecorder(10)
+	r := &CronJobReconciler{Clock: clock, Recorder: recorder}
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.Time{Time: created}},
+		Spec: batchv1.CronJobSpec{
+			SuccessDeadline: &metav1.Duration{Duration: 26 * time.Hour},
+			Notifications:   []batchv1.Notification{{Name: "oncall", Events: []batchv1.NotificationEvent{batchv1.StaleEvent}}},
+		},
+	}
+
+	// never succeeded, but still young
+	clock.now = hours(10)
+	message, pending, next := r.checkStale(cronJob)
+	if message != "" || len(pending) != 0 || !next.Equal(hours(26)) || meta.IsStatusConditionTrue(cronJob.Status.Conditions, batchv1.StaleCondition) {
+		t.Errorf("checkStale() at 10h = %d notifications, next %s; want none, next %s", len(pending), next, hours(26))
+	}
+
+	// going stale is reported once, after the status update
+	clock.now = hours(30)
+	if message, pending, _ = r.checkStale(cronJob); message == "" || len(pending) != 1 || pending[0].notification.Event != batchv1.StaleEvent {
+		t.Errorf("checkStale() at 30h = %q, %v, want an Event and a Stale notification", message, pending)
+	}
+	if len(recorder.Events) != 0 {
+		t.Errorf("checkStale() emitted %q before the status was updated", <-recorder.Events)
+	}
+	if !meta.IsStatusConditionTrue(cronJob.Status.Conditions, batchv1.StaleCondition) {
+		t.Error("Stale condition isn't true at 30h")
+	}
+	clock.now = hours(31)
+	if message, pending, _ = r.checkStale(cronJob); message != "" || len(pending) != 0 {
+		t.Errorf("checkStale() at 31h = %q, %v, want no more Events or notifications", message, pending)
+	}
+
+	// a success puts it right again
+	cronJob.Status.LastSuccessfulTime = &metav1.Time{Time: hours(31)}
+	message, pending, next = r.checkStale(cronJob)
+	if message != "" || len(pending) != 0 || !next.Equal(hours(57)) || meta.IsStatusConditionTrue(cronJob.Status.Conditions, batchv1.StaleCondition) {
+		t.Errorf("checkStale() after a success = %d notifications, next %s; want none, next %s", len(pending), next, hours(57))
+	}
+
+	cronJob.Spec.SuccessDeadline = nil
+	r.checkStale(cronJob)
+	if meta.FindStatusCondition(cronJob.Status.Conditions, batchv1.StaleCondition) != nil {
+		t.Error("Stale condition is still there without a success deadline")
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	batchv1 "tutorial.kubebuilder.io/project/api/v1"
+)
+
+// jobDuration tells how long a finished job took, from its start to when it completed or
+// failed, or 0 if we can't tell. Only Jobs say when they started.
+func jobDuration(job client.Object) time.Duration {
+	kjob, ok := job.(*kbatch.Job)
+	if !ok || kjob.Status.StartTime == nil {
+		return 0
+	}
+	end := kjob.Status.CompletionTime
+	for i := range kjob.Status.Conditions {
+		if c := &kjob.Status.Conditions[i]; end == nil && c.Type == kbatch.JobFailed && c.Status == corev1.ConditionTrue {
+			end = &c.LastTransitionTime
+		}
+	}
+	if end == nil {
+		return 0
+	}
+	return end.Sub(kjob.Status.StartTime.Time)
+}
+
+/*
+recordDurations adds how long the runs that were just counted took to
+status.RecentDurations, keeping the latest MaxRecentDurations of them, and works out the
+statistics over those. Counting takes every run once, so every run makes it in once as
+well, and the Jobs being cleaned up doesn't change the statistics.
+*/
+func recordDurations(status *batchv1.CronJobStatus, counted []scheduledRun) {
+	for _, run := range counted {
+		if run.duration > 0 {
+			status.RecentDurations = append(status.RecentDurations, metav1.Duration{Duration: run.duration})
+		}
+	}
+	if extra := len(status.RecentDurations) - batchv1.MaxRecentDurations; extra > 0 {
+		status.RecentDurations = status.RecentDurations[extra:]
+	}
+	status.DurationStatistics = durationStatistics(status.RecentDurations)
+}
+
+// durationStatistics works out the statistics of the durations, using the nearest rank
+// for the percentiles.
+func durationStatistics(durations []metav1.Duration) *batchv1.DurationStatistics {
+	if len(durations) == 0 {
+		return nil
+	}
+	sorted := make([]time.Duration, len(durations))
+	for i, d := range durations {
+		sorted[i] = d.Duration
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	percentile := func(p int) metav1.Duration {
+		rank := (p*len(sorted) + 99) / 100
+		return metav1.Duration{Duration: sorted[rank-1]}
+	}
+	return &batchv1.DurationStatistics{
+		Runs: int32(len(sorted)),
+		P50:  percentile(50),
+		P95:  percentile(95),
+		Max:  metav1.Duration{Duration: sorted[len(sorted)-1]},
+	}
+}
+
+/*
+setDurationExceedsInterval sets the DurationExceedsInterval condition, which warns that
+the runs take longer than the schedule leaves them: from then on, the next tick comes
+while a run is still going, which the ConcurrencyPolicy has to deal with one way or
+another. We go by the p95 duration, so that a single slow run doesn't set it off.
+
+Finding the shortest interval steps through up to a year of the schedule, so we only do it
+again when the spec or the p95 duration changed since the condition was set, i.e. from
+oldStats, the statistics the status had before this reconcile.
+*/
+func setDurationExceedsInterval(cronJob *batchv1.CronJob, oldStats *batchv1.DurationStatistics, now time.Time) {
+	stats := cronJob.Status.DurationStatistics
+	if stats == nil {
+		meta.RemoveStatusCondition(&cronJob.Status.Conditions, batchv1.DurationExceedsIntervalCondition)
+		return
+	}
+	condition := meta.FindStatusCondition(cronJob.Status.Conditions, batchv1.DurationExceedsIntervalCondition)
+	if condition != nil && condition.ObservedGeneration == cronJob.Generation &&
+		oldStats != nil && oldStats.P95 == stats.P95 {
+		return
+	}
+	// any interval shorter than the p95 duration will do for the warning
+	interval, err := batchv1.ShortestInterval(cronJob.Spec.Schedule, now, stats.P95.Duration)
+	if err != nil || interval == 0 {
+		// an unparseable schedule is reported further down
+		meta.RemoveStatusCondition(&cronJob.Status.Conditions, batchv1.DurationExceedsIntervalCondition)
+		return
+	}
+
+	if stats.P95.Duration <= interval {
+		meta.SetStatusCondition(&cronJob.Status.Conditions, metav1.Condition{
+			Type:               batchv1.DurationExceedsIntervalCondition,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: cronJob.Generation,
+			Reason:             "WithinInterval",
+			Message:            fmt.Sprintf("The p95 duration of %s fits into the schedule interval of %s", stats.P95.Duration, interval),
+		})
+		return
+	}
+
+	var consequence string
+	switch cronJob.Spec.ConcurrencyPolicy {
+	case batchv1.ForbidConcurrent:
+		consequence = "runs will be skipped"
+	case batchv1.ReplaceConcurrent:
+		consequence = "runs will be replaced before they finish"
+	default:
+		consequence = "runs will overlap"
+	}
+	meta.SetStatusCondition(&cronJob.Status.Conditions, metav1.Condition{
+		Type:               batchv1.DurationExceedsIntervalCondition,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: cronJob.Generation,
+		Reason:             "P95ExceedsInterval",
+		Message: fmt.Sprintf("The p95 duration of %s is longer than the schedule interval of %s, so %s",
+			stats.P95.Duration, interval, consequence),
+	})
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	batchv1 "tutorial.kubebuilder.io/project/api/v1"
+)
+
+func TestRecordDurations(t *testing.T) {
+	var runs []scheduledRun
+	for m := 1; m <= 25; m++ {
+		runs = append(runs, scheduledRun{duration: time.Duration(m) * time.Minute})
+	}
+	// runs we can't tell the duration of are left out
+	runs = append(runs, scheduledRun{})
+
+	status := &batchv1.CronJobStatus{}
+	recordDurations(status, runs)
+	if len(status.RecentDurations) != batchv1.MaxRecentDurations || status.RecentDurations[0].Duration != 6*time.Minute {
+		t.Fatalf("RecentDurations = %v, want the latest %d, from 6m", status.RecentDurations, batchv1.MaxRecentDurations)
+	}
+	want := batchv1.DurationStatistics{
+		Runs: 20,
+		P50:  metav1.Duration{Duration: 15 * time.Minute},
+		P95:  metav1.Duration{Duration: 24 * time.Minute},
+		Max:  metav1.Duration{Duration: 25 * time.Minute},
+	}
+	if got := status.DurationStatistics; got == nil || *got != want {
+		t.Errorf("DurationStatistics = %+v, want %+v", got, want)
+	}
+}
+
+func TestSetDurationExceedsInterval(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	for name, tc := range map[string]struct {
+		p95  time.Duration
+		want metav1.ConditionStatus
+	}{
+		"within":  {50 * time.Minute, metav1.ConditionFalse},
+		"exactly": {time.Hour, metav1.ConditionFalse},
+		"over":    {70 * time.Minute, metav1.ConditionTrue},
+	} {
+		cronJob := &batchv1.CronJob{
+			Spec:   batchv1.CronJobSpec{Schedule: "0 * * * *", ConcurrencyPolicy: batchv1.ForbidConcurrent},
+			Status: batchv1.CronJobStatus{DurationStatistics: &batchv1.DurationStatistics{P95: metav1.Duration{Duration: tc.p95}}},
+		}
+		setDurationExceedsInterval(cronJob, nil, now)
+		condition := meta.FindStatusCondition(cronJob.Status.Conditions, batchv1.DurationExceedsIntervalCondition)
+		if condition == nil || condition.Status != tc.want {
+			t.Errorf("%s: condition = %+v, want status %s", name, condition, tc.want)
+		}
+	}
+
+	cronJob := &batchv1.CronJob{Spec: batchv1.CronJobSpec{Schedule: "0 * * * *"}}
+	setDurationExceedsInterval(cronJob, nil, now)
+	if meta.FindStatusCondition(cronJob.Status.Conditions, batchv1.DurationExceedsIntervalCondition) != nil {
+		t.Error("condition set without any durations")
+	}
+
+	t.Run("works the interval out again only when needed", func(t *testing.T) {
+		stats := func(p95 time.Duration) *batchv1.DurationStatistics {
+			return &batchv1.DurationStatistics{P95: metav1.Duration{Duration: p95}}
+		}
+		cronJob := &batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Generation: 1},
+			Spec:       batchv1.CronJobSpec{Schedule: "0 * * * *"},
+			Status:     batchv1.CronJobStatus{DurationStatistics: stats(50 * time.Minute)},
+		}
+		setDurationExceedsInterval(cronJob, nil, now)
+		status := func() metav1.ConditionStatus {
+			return meta.FindStatusCondition(cronJob.Status.Conditions, batchv1.DurationExceedsIntervalCondition).Status
+		}
+		if got := status(); got != metav1.ConditionFalse {
+			t.Fatalf("condition status = %s, want False", got)
+		}
+
+		// an unchanged schedule and p95 keep the condition as it is, even if it went stale
+		cronJob.Spec.Schedule = "*/5 * * * *"
+		setDurationExceedsInterval(cronJob, stats(50*time.Minute), now)
+		if got := status(); got != metav1.ConditionFalse {
+			t.Errorf("condition status = %s without a change, want False still", got)
+		}
+		// a new generation of the spec does not
+		cronJob.Generation = 2
+		setDurationExceedsInterval(cronJob, stats(50*time.Minute), now)
+		if got := status(); got != metav1.ConditionTrue {
+			t.Errorf("condition status = %s after a change of the schedule, want True", got)
+		}
+		// and neither does a new p95
+		cronJob.Spec.Schedule = "0 * * * *"
+		cronJob.Status.DurationStatistics = stats(70 * time.Minute)
+		setDurationExceedsInterval(cronJob, stats(50*time.Minute), now)
+		if got := status(); got != metav1.ConditionTrue {
+			t.Errorf("condition status = %s after a change of the p95, want True", got)
+		}
+		cronJob.Status.DurationStatistics = stats(40 * time.Minute)
+		setDurationExceedsInterval(cronJob, stats(70*time.Minute), now)
+		if got := status(); got != metav1.ConditionFalse {
+			t.Errorf("condition status = %s after a change of the p95, want False", got)
+		}
+	})
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	configv1alpha1 "tutorial.kubebuilder.io/project/api/config/v1alpha1"
+)
+
+// managerFlags holds the command-line flags of the manager that have a counterpart in its
+// configuration file.
+type managerFlags struct {
+	metricsAddr             string
+	enableLeaderElection    bool
+	probeAddr               string
+	webhookPort             int
+	webhookCertDir          string
+	watchNamespaces         string
+	shards                  int
+	shardLeaseNamespace     string
+	maxConcurrentReconciles int
+	rateLimiterBaseDelay    time.Duration
+	rateLimiterMaxDelay     time.Duration
+	rateLimiterQPS          float64
+	rateLimiterBurst        int
+	syncPeriod              time.Duration
+}
+
+// bind defines the flags on fs.
+func (f *managerFlags) bind(fs *flag.FlagSet) {
+	fs.StringVar(&f.metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
+	fs.StringVar(&f.probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	fs.BoolVar(&f.enableLeaderElection, "leader-elect", false,
+		"Enable leader election for controller manager. "+
+			"Enabling this will ensure there is only one active controller manager.")
+	fs.IntVar(&f.webhookPort, "webhook-port", 9443, "The port the webhook server listens on.")
+	fs.StringVar(&f.webhookCertDir, "webhook-cert-dir", "",
+		"The directory holding the serving certificate of the webhook server. "+
+			"Defaults to <temp-dir>/k8s-webhook-server/serving-certs.")
+	fs.StringVar(&f.watchNamespaces, "watch-namespaces", "",
+		"Comma-separated list of namespaces the manager watches. "+
+			"CronJobs in other namespaces are left alone by both the controller and the webhook. "+
+			"Defaults to all namespaces.")
+	fs.IntVar(&f.shards, "shards", 0,
+		"Split CronJobs into this many shards, claimed by the replicas of the manager through Leases. "+
+			"Every replica is active in sharding mode, so it can't be combined with --leader-elect. "+
+			"Disabled if 0.")
+	fs.StringVar(&f.shardLeaseNamespace, "shard-lease-namespace", "",
+		"The namespace the shard Leases are kept in. Defaults to the namespace of the manager.")
+	fs.IntVar(&f.maxConcurrentReconciles, "max-concurrent-reconciles", 1,
+		"The number of CronJobs reconciled in parallel. Reconciles of the same CronJob never overlap.")
+	fs.DurationVar(&f.rateLimiterBaseDelay, "rate-limiter-base-delay", 5*time.Millisecond,
+		"The delay before the first retry of a failed reconcile, doubled on every further failure of the same CronJob.")
+	fs.DurationVar(&f.rateLimiterMaxDelay, "rate-limiter-max-delay", 1000*time.Second,
+		"The maximum delay between retries of a failed reconcile of the same CronJob.")
+	fs.Float64Var(&f.rateLimiterQPS, "rate-limiter-qps", 10,
+		"The overall number of retries per second, across all CronJobs.")
+	fs.IntVar(&f.rateLimiterBurst, "rate-limiter-burst", 100,
+		"The overall burst of retries, across all CronJobs.")
+	fs.DurationVar(&f.syncPeriod, "sync-period", 10*time.Hour,
+		"The interval at which the cache is resynced and every CronJob is reconciled again.")
+}
+
+/*
+applyTo merges the flags, once fs is parsed, into the options and the ProjectConfig read
+from the configuration file. A flag given on the command line always wins; otherwise the
+file wins over the default of the flag.
+*/
+func (f *managerFlags) applyTo(fs *flag.FlagSet, options *ctrl.Options, config *configv1alpha1.ProjectConfig) {
+	flagSet := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { flagSet[f.Name] = true })
+	// use tells whether the flag should be used rather than the value from the file
+	use := func(name string, unsetInFile bool) bool {
+		return flagSet[name] || unsetInFile
+	}
+
+	if use("metrics-bind-address", options.MetricsBindAddress == "") {
+		options.MetricsBindAddress = f.metricsAddr
+	}
+	if use("health-probe-bind-address", options.HealthProbeBindAddress == "") {
+		options.HealthProbeBindAddress = f.probeAddr
+	}
+	if use("leader-elect", !options.LeaderElection) {
+		options.LeaderElection = f.enableLeaderElection
+	}
+	if use("webhook-port", options.Port == 0) {
+		options.Port = f.webhookPort
+	}
+	if use("webhook-cert-dir", options.CertDir == "") {
+		options.CertDir = f.webhookCertDir
+	}
+	if use("sync-period", options.SyncPeriod == nil) {
+		syncPeriod := f.syncPeriod
+		options.SyncPeriod = &syncPeriod
+	}
+
+	if use("watch-namespaces", len(config.WatchNamespaces) == 0) {
+		config.WatchNamespaces = splitNamespaces(f.watchNamespaces)
+	}
+	sharding := &config.Sharding
+	if use("shards", sharding.Shards == 0) {
+		sharding.Shards = f.shards
+	}
+	if use("shard-lease-namespace", sharding.LeaseNamespace == "") {
+		sharding.LeaseNamespace = f.shardLeaseNamespace
+	}
+	controllerConfig := &config.CronJobController
+	if use("max-concurrent-reconciles", controllerConfig.MaxConcurrentReconciles == 0) {
+		controllerConfig.MaxConcurrentReconciles = f.maxConcurrentReconciles
+	}
+	rateLimiter := &controllerConfig.RateLimiter
+	if use("rate-limiter-base-delay", rateLimiter.BaseDelay == nil) {
+		rateLimiter.BaseDelay = &metav1.Duration{Duration: f.rateLimiterBaseDelay}
+	}
+	if use("rate-limiter-max-delay", rateLimiter.MaxDelay == nil) {
+		rateLimiter.MaxDelay = &metav1.Duration{Duration: f.rateLimiterMaxDelay}
+	}
+	if use("rate-limiter-qps", rateLimiter.QPS == nil) {
+		qps := f.rateLimiterQPS
+		rateLimiter.QPS = &qps
+	}
+	if use("rate-limiter-burst", rateLimiter.Burst == nil) {
+		burst := f.rateLimiterBurst
+		rateLimiter.Burst = &burst
+	}
+}
+
+// splitNamespaces turns the value of --watch-namespaces into a list, dropping empty entries.
+func splitNamespaces(value string) []string {
+	var namespaces []string
+	for _, ns := range strings.Split(value, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	configv1alpha1 "tutorial.kubebuilder.io/project/api/config/v1alpha1"
+)
+
+func TestManagerFlagsApplyTo(t *testing.T) {
+	// settings are some of the settings of each kind that end up in the options or the config
+	type settings struct {
+		metricsAddr     string
+		leaderElection  bool
+		syncPeriod      time.Duration
+		watchNamespaces []string
+		shards          int
+		baseDelay       time.Duration
+		qps             float64
+	}
+	defaults := settings{metricsAddr: ":8080", syncPeriod: 10 * time.Hour, baseDelay: 5 * time.Millisecond, qps: 10}
+	fromFlags := settings{metricsAddr: ":9090", syncPeriod: time.Hour, watchNamespaces: []string{"team-a", "team-b"}, shards: 2, baseDelay: time.Second, qps: 1}
+	args := []string{"--metrics-bind-address=:9090", "--leader-elect=false", "--sync-period=1h", "--watch-namespaces=team-a,team-b",
+		"--shards=2", "--rate-limiter-base-delay=1s", "--rate-limiter-qps=1"}
+	fromFile := settings{metricsAddr: ":7070", leaderElection: true, syncPeriod: 2 * time.Hour, watchNamespaces: []string{"team-c"}, shards: 3, baseDelay: time.Minute, qps: 5}
+	file := func(options *ctrl.Options, config *configv1alpha1.ProjectConfig) {
+		syncPeriod, qps := fromFile.syncPeriod, fromFile.qps
+		options.MetricsBindAddress = fromFile.metricsAddr
+		options.LeaderElection = fromFile.leaderElection
+		options.SyncPeriod = &syncPeriod
+		config.WatchNamespaces = fromFile.watchNamespaces
+		config.Sharding.Shards = fromFile.shards
+		config.CronJobController.RateLimiter.BaseDelay = &metav1.Duration{Duration: fromFile.baseDelay}
+		config.CronJobController.RateLimiter.QPS = &qps
+	}
+
+	for name, tc := range map[string]struct {
+		args []string
+		file func(*ctrl.Options, *configv1alpha1.ProjectConfig)
+		want settings
+	}{
+		"neither":   {want: defaults},
+		"flag only": {args: args, want: fromFlags},
+		"file only": {file: file, want: fromFile},
+		"both":      {args: args, file: file, want: fromFlags},
+	} {
+		fs := flag.NewFlagSet("manager", flag.ContinueOnError)
+		var flags managerFlags
+		flags.bind(fs)
+		if err := fs.Parse(tc.args); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		options, config := ctrl.Options{}, configv1alpha1.ProjectConfig{}
+		if tc.file != nil {
+			tc.file(&options, &config)
+		}
+		flags.applyTo(fs, &options, &config)
+
+		rateLimiter := config.CronJobController.RateLimiter
+		got := settings{
+			metricsAddr:     options.MetricsBindAddress,
+			leaderElection:  options.LeaderElection,
+			syncPeriod:      *options.SyncPeriod,
+			watchNamespaces: config.WatchNamespaces,
+			shards:          config.Sharding.Shards,
+			baseDelay:       rateLimiter.BaseDelay.Duration,
+			qps:             *rateLimiter.QPS,
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %+v, want %+v", name, got, tc.want)
+		}
+		// the rest of the settings fall back to the defaults of their flags
+		if options.Port != 9443 || config.CronJobController.MaxConcurrentReconciles != 1 ||
+			rateLimiter.MaxDelay.Duration != 1000*time.Second || *rateLimiter.Burst != 100 {
+			t.Errorf("%s: got port %d, %d concurrent reconciles, max delay %s and burst %d, want the defaults", name,
+				options.Port, config.CronJobController.MaxConcurrentReconciles, rateLimiter.MaxDelay.Duration, *rateLimiter.Burst)
+		}
+	}
+}
+
+func TestSplitNamespaces(t *testing.T) {
+	for value, want := range map[string][]string{
+		"":                  nil,
+		"team-a":            {"team-a"},
+		"team-a,team-b":     {"team-a", "team-b"},
+		" team-a , team-b ": {"team-a", "team-b"},
+		"team-a,,team-b,":   {"team-a", "team-b"},
+		" , ":               nil,
+	} {
+		if got := splitNamespaces(value); !reflect.DeepEqual(got, want) {
+			t.Errorf("splitNamespaces(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
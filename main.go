@@ -19,6 +19,7 @@ package main
 import (
 	"flag"
 	"os"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -26,11 +27,14 @@ import (
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
+	configv1alpha1 "tutorial.kubebuilder.io/project/api/config/v1alpha1"
 	batchv1 "tutorial.kubebuilder.io/project/api/v1"
 	"tutorial.kubebuilder.io/project/controllers"
 	//+kubebuilder:scaffold:imports
@@ -45,18 +49,18 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(batchv1.AddToScheme(scheme))
+	utilruntime.Must(configv1alpha1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
 func main() {
-	var metricsAddr string
-	var enableLeaderElection bool
-	var probeAddr string
-	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
-	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
-	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
-		"Enable leader election for controller manager. "+
-			"Enabling this will ensure there is only one active controller manager.")
+	var configFile string
+	flag.StringVar(&configFile, "config", "",
+		"The manager configuration file, see config/manager/controller_manager_config.yaml. "+
+			"Command-line flags override the settings in the file.")
+	var flags managerFlags
+	flags.bind(flag.CommandLine)
+
 	opts := zap.Options{
 		Development: true,
 	}
@@ -65,37 +69,114 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:                 scheme,
-		MetricsBindAddress:     metricsAddr,
-		Port:                   9443,
-		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "80807133.tutorial.kubebuilder.io",
-		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
-		// when the Manager ends. This requires the binary to immediately end when the
-		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
-		// speeds up voluntary leader transitions as the new leader don't have to wait
-		// LeaseDuration time first.
-		//
-		// In the default scaffold provided, the program ends immediately after
-		// the manager stops, so would be fine to enable this option. However,
-		// if you are doing or is intended to do any operation such as perform cleanups
-		// after the manager stops then its usage might be unsafe.
-		// LeaderElectionReleaseOnCancel: true,
-	})
+	/*
+		The manager can be configured through a versioned configuration file (a ProjectConfig,
+		see api/config/v1alpha1), through flags, or both. A flag given on the command line
+		always wins; otherwise the file wins over the default of the flag, see flags.go.
+	*/
+	options := ctrl.Options{Scheme: scheme}
+	projectConfig := configv1alpha1.ProjectConfig{}
+	if configFile != "" {
+		var err error
+		options, err = options.AndFrom(ctrl.ConfigFile().AtPath(configFile).OfKind(&projectConfig))
+		if err != nil {
+			setupLog.Error(err, "unable to load the config file")
+			os.Exit(1)
+		}
+	}
+
+	flags.applyTo(flag.CommandLine, &options, &projectConfig)
+	if options.LeaderElectionID == "" {
+		options.LeaderElectionID = "80807133.tutorial.kubebuilder.io"
+	}
+	// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
+	// when the Manager ends. This requires the binary to immediately end when the
+	// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
+	// speeds up voluntary leader transitions as the new leader don't have to wait
+	// LeaseDuration time first.
+	//
+	// In the default scaffold provided, the program ends immediately after
+	// the manager stops, so would be fine to enable this option. However,
+	// if you are doing or is intended to do any operation such as perform cleanups
+	// after the manager stops then its usage might be unsafe.
+	// options.LeaderElectionReleaseOnCancel = true
+
+	namespaces := projectConfig.WatchNamespaces
+	sharding := projectConfig.Sharding
+	controllerConfig := projectConfig.CronJobController
+	rateLimiter := controllerConfig.RateLimiter
+
+	if limit := projectConfig.CronJobDefaults.SuccessfulJobsHistoryLimit; limit != nil {
+		batchv1.DefaultSuccessfulJobsHistoryLimit = *limit
+	}
+	if limit := projectConfig.CronJobDefaults.FailedJobsHistoryLimit; limit != nil {
+		batchv1.DefaultFailedJobsHistoryLimit = *limit
+	}
+
+	if sharding.Shards > 0 && options.LeaderElection {
+		setupLog.Error(nil, "sharding and leader election can't be used together")
+		os.Exit(1)
+	}
+
+	/*
+		By default the manager caches objects of the whole cluster. When we are asked to watch
+		a set of namespaces only, we build a cache per namespace instead, so that the manager
+		can run with a namespaced Role (see config/rbac/namespaced) rather than a ClusterRole.
+	*/
+	if len(namespaces) > 0 {
+		setupLog.Info("restricting manager to namespaces", "namespaces", namespaces)
+		options.NewCache = cache.MultiNamespacedCacheBuilder(namespaces)
+	}
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), options)
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	/*
+		In sharding mode, each replica claims some of the shards through Leases and only
+		reconciles the CronJobs that hash into them. See controllers/sharding.go.
+	*/
+	var shardManager *controllers.ShardManager
+	if sharding.Shards > 0 {
+		shardManager, err = newShardManager(mgr, sharding.Shards, sharding.LeaseNamespace)
+		if err != nil {
+			setupLog.Error(err, "unable to set up sharding")
+			os.Exit(1)
+		}
+		if err := mgr.Add(shardManager); err != nil {
+			setupLog.Error(err, "unable to set up sharding")
+			os.Exit(1)
+		}
+	}
+
 	if err = (&controllers.CronJobReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:          mgr.GetClient(),
+		Scheme:          mgr.GetScheme(),
+		Recorder:        mgr.GetEventRecorderFor("cronjob-controller"),
+		WatchNamespaces: namespaces,
+		Shards:          shardManager,
+		APIReader:       mgr.GetAPIReader(),
+
+		MaxConcurrentReconciles: controllerConfig.MaxConcurrentReconciles,
+		RateLimiter: controllers.NewRateLimiter(rateLimiter.BaseDelay.Duration, rateLimiter.MaxDelay.Duration,
+			*rateLimiter.QPS, *rateLimiter.Burst),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "CronJob")
 		os.Exit(1)
 	}
+	if err = (&controllers.ScheduledJobReconciler{
+		Client:          mgr.GetClient(),
+		Scheme:          mgr.GetScheme(),
+		Recorder:        mgr.GetEventRecorderFor("scheduledjob-controller"),
+		APIReader:       mgr.GetAPIReader(),
+		WatchNamespaces: namespaces,
+		Shards:          shardManager,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ScheduledJob")
+		os.Exit(1)
+	}
 
 	/*
 		We will also set up webhooks for our type, which we will talk about next. We just
@@ -104,10 +185,14 @@ func main() {
 		environment variable
 	*/
 	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
-		if err = (&batchv1.CronJob{}).SetupWebhookWithManager(mgr); err != nil {
+		if err = (&batchv1.CronJob{}).SetupWebhookWithManager(mgr, namespaces...); err != nil {
 			setupLog.Error(err, "unable to create webhook", "webhook", "CronJob")
 			os.Exit(1)
 		}
+		if err = (&batchv1.ScheduledJob{}).SetupWebhookWithManager(mgr, namespaces...); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ScheduledJob")
+			os.Exit(1)
+		}
 	}
 
 	// if err = (&batchv1.CronJob{}).SetupWebhookWithManager(mgr); err != nil {
@@ -131,3 +216,31 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// newShardManager sets up the ShardManager of this replica. Replicas are told apart by
+// their host name, which is the pod name when running in the cluster.
+func newShardManager(mgr ctrl.Manager, shards int, leaseNamespace string) (*controllers.ShardManager, error) {
+	identity, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	if leaseNamespace == "" {
+		leaseNamespace = inClusterNamespace()
+	}
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return nil, err
+	}
+	setupLog.Info("sharding enabled", "shards", shards, "identity", identity, "lease namespace", leaseNamespace)
+	return controllers.NewShardManager(clientset.CoordinationV1().Leases(leaseNamespace), mgr.GetClient(), identity, shards), nil
+}
+
+// inClusterNamespace returns the namespace the manager runs in, or "default" when
+// running outside of the cluster.
+func inClusterNamespace() string {
+	ns, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
+	if err != nil {
+		return "default"
+	}
+	return strings.TrimSpace(string(ns))
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	batchv1 "tutorial.kubebuilder.io/project/api/v1"
+)
+
+// CloudEventTypePrefix prefixes the type of the CloudEvents, which ends in the event, e.g.
+// "io.kubebuilder.tutorial.batch.cronjob.Failure".
+const CloudEventTypePrefix = "io.kubebuilder.tutorial.batch.cronjob."
+
+// CloudEvents posts notifications as CloudEvents 1.0 over HTTP, in binary content mode: the
+// attributes go into ce-* headers, the data is the JSON payload.
+type CloudEvents struct {
+	URL     string
+	Headers http.Header
+
+	// Client defaults to one that doesn't follow redirects
+	Client *http.Client
+}
+
+// Notify implements Notifier.
+func (c *CloudEvents) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(newPayload(n))
+	if err != nil {
+		return err
+	}
+	headers := c.Headers.Clone()
+	if headers == nil {
+		headers = make(http.Header)
+	}
+	headers.Set("ce-specversion", "1.0")
+	headers.Set("ce-id", n.ID)
+	headers.Set("ce-type", CloudEventTypePrefix+string(n.Event))
+	headers.Set("ce-source", fmt.Sprintf("/apis/%s/namespaces/%s/cronjobs/%s",
+		batchv1.GroupVersion, n.CronJob.Namespace, n.CronJob.Name))
+	headers.Set("ce-time", n.Time.UTC().Format(time.RFC3339))
+	if n.Job != "" {
+		headers.Set("ce-subject", n.Job)
+	}
+	return post(ctx, c.Client, c.URL, headers, body)
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package notify delivers notifications about the runs of CronJobs. Each kind of sink is a
+// Notifier; the controller works out what to notify about, and whom.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	batchv1 "tutorial.kubebuilder.io/project/api/v1"
+)
+
+// Notification is what a Notifier tells its sink about.
+type Notification struct {
+	// ID identifies the notification, so that sinks can tell duplicates apart
+	ID string
+
+	Event   batchv1.NotificationEvent
+	CronJob types.NamespacedName
+
+	// The scheduled time of the run, or when the CronJob went stale
+	ScheduledTime time.Time
+
+	// The Job of the run the notification is about, if any
+	Job string
+
+	// A human-readable description of what happened
+	Message string
+
+	// When it happened
+	Time time.Time
+}
+
+// A Notifier delivers notifications to one kind of sink.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// payload is the JSON representation of a notification. Slack shows the text, and ignores
+// the rest.
+type payload struct {
+	Text          string                    `json:"text"`
+	Event         batchv1.NotificationEvent `json:"event"`
+	Namespace     string                    `json:"namespace"`
+	CronJob       string                    `json:"cronJob"`
+	ScheduledTime time.Time                 `json:"scheduledTime"`
+	Job           string                    `json:"job,omitempty"`
+	Time          time.Time                 `json:"time"`
+}
+
+func newPayload(n Notification) payload {
+	return payload{
+		Text:          n.Message,
+		Event:         n.Event,
+		Namespace:     n.CronJob.Namespace,
+		CronJob:       n.CronJob.Name,
+		ScheduledTime: n.ScheduledTime,
+		Job:           n.Job,
+		Time:          n.Time,
+	}
+}
+
+// defaultClient doesn't follow redirects: the headers may hold credentials meant for the URL
+// of the sink only, and that URL is the one the webhook checked against the policies.
+var defaultClient = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// post sends the body to the URL, and fails unless the response has a 2xx status.
+func post(ctx context.Context, client *http.Client, url string, headers http.Header, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = headers.Clone()
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if client == nil {
+		client = defaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status %q", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	batchv1 "tutorial.kubebuilder.io/project/api/v1"
+)
+
+var notification = Notification{
+	ID:            "uid-1672531200-failure",
+	Event:         batchv1.FailureEvent,
+	CronJob:       types.NamespacedName{Namespace: "team-a", Name: "nightly"},
+	ScheduledTime: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+	Job:           "nightly-1672531200",
+	Message:       "The run of CronJob team-a/nightly at 2023-01-01T00:00:00Z failed",
+	Time:          time.Date(2023, 1, 1, 0, 5, 0, 0, time.UTC),
+}
+
+// recorder is a sink that keeps the last request it got, and answers with status.
+func recorder(status int) (*httptest.Server, *http.Request, *payload) {
+	var got http.Request
+	var body payload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = *req
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		w.WriteHeader(status)
+	}))
+	return server, &got, &body
+}
+
+func TestWebhook(t *testing.T) {
+	server, req, body := recorder(http.StatusOK)
+	defer server.Close()
+
+	webhook := &Webhook{URL: server.URL, Headers: http.Header{"Authorization": []string{"Bearer token"}}}
+	if err := webhook.Notify(context.Background(), notification); err != nil {
+		t.Fatalf("Notify() failed: %v", err)
+	}
+	if req.Header.Get("Authorization") != "Bearer token" || req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("got headers %v", req.Header)
+	}
+	if body.Text != notification.Message || body.Event != batchv1.FailureEvent || body.Job != notification.Job {
+		t.Errorf("got payload %+v", body)
+	}
+
+	failing, _, _ := recorder(http.StatusInternalServerError)
+	defer failing.Close()
+	if err := (&Webhook{URL: failing.URL}).Notify(context.Background(), notification); err == nil {
+		t.Error("Notify() ignored an error response")
+	}
+}
+
+func TestCloudEvents(t *testing.T) {
+	server, req, body := recorder(http.StatusAccepted)
+	defer server.Close()
+
+	if err := (&CloudEvents{URL: server.URL}).Notify(context.Background(), notification); err != nil {
+		t.Fatalf("Notify() failed: %v", err)
+	}
+	for header, want := range map[string]string{
+		"ce-specversion": "1.0",
+		"ce-id":          notification.ID,
+		"ce-type":        "io.kubebuilder.tutorial.batch.cronjob.Failure",
+		"ce-source":      "/apis/batch.tutorial.kubebuilder.io/v1/namespaces/team-a/cronjobs/nightly",
+		"ce-time":        "2023-01-01T00:05:00Z",
+		"ce-subject":     notification.Job,
+	} {
+		if got := req.Header.Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+	if body.CronJob != "nightly" || body.Namespace != "team-a" {
+		t.Errorf("got data %+v", body)
+	}
+}
+
+func TestEmailMessage(t *testing.T) {
+	email := &Email{From: "cron@example.com", To: []string{"a@example.com", "b@example.com"}}
+	msg := string(email.message(notification))
+	for _, want := range []string{
+		"To: a@example.com, b@example.com\r\n",
+		"Subject: [Failure] CronJob team-a/nightly: 2023-01-01T00:00:00Z\r\n",
+		"\r\n\r\n" + notification.Message + "\r\n",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message %q doesn't contain %q", msg, want)
+		}
+	}
+}
+
+func TestEmailGivesUpWithContext(t *testing.T) {
+	// a server that takes the connection, and never greets
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			_, _ = io.Copy(io.Discard, conn)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	email := &Email{Server: listener.Addr().String(), From: "cron@example.com", To: []string{"a@example.com"}}
+	done := make(chan error, 1)
+	go func() { done <- email.Notify(ctx, notification) }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Notify() succeeded without a greeting")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Notify() is still waiting for the server after its context is done")
+	}
+}